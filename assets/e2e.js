@@ -0,0 +1,48 @@
+// End-to-end encryption for Tempsy, the key never leave the browser.
+//
+// Format:
+// - key: 32 random bytes AES-256-GCM key, base64url without padding, placed in url fragment `#<key>`
+// - header: `v1.<base64url iv>`, stored by the server as `file-e2e-header`
+// - file: AES-256-GCM cipher text (with 16 bytes tag), uploaded as `application/octet-stream`
+
+const VERSION = 'v1'
+
+export function toBase64Url(bytes) {
+  let binary = ''
+  for (const byte of new Uint8Array(bytes)) {
+    binary += String.fromCharCode(byte)
+  }
+
+  return btoa(binary).replaceAll('+', '-').replaceAll('/', '_').replace(/=+$/, '')
+}
+
+export function fromBase64Url(text) {
+  const base64 = text.replaceAll('-', '+').replaceAll('_', '/')
+  const binary = atob(base64.padEnd(base64.length + ((4 - (base64.length % 4)) % 4), '='))
+
+  return Uint8Array.from(binary, char => char.charCodeAt(0))
+}
+
+export async function encryptFile(plain) {
+  const rawKey = crypto.getRandomValues(new Uint8Array(32))
+  const iv = crypto.getRandomValues(new Uint8Array(12))
+  const key = await crypto.subtle.importKey('raw', rawKey, 'AES-GCM', false, ['encrypt'])
+  const cipherText = await crypto.subtle.encrypt({ name: 'AES-GCM', iv }, key, plain)
+
+  return {
+    key: toBase64Url(rawKey),
+    header: `${VERSION}.${toBase64Url(iv)}`,
+    cipherText,
+  }
+}
+
+export async function decryptFile(cipherText, header, rawKey) {
+  const [version, iv] = header.split('.')
+  if (version !== VERSION || !iv) {
+    throw new Error(`Unsupported encryption header: ${header}`)
+  }
+
+  const key = await crypto.subtle.importKey('raw', fromBase64Url(rawKey), 'AES-GCM', false, ['decrypt'])
+
+  return crypto.subtle.decrypt({ name: 'AES-GCM', iv: fromBase64Url(iv) }, key, cipherText)
+}
@@ -5,13 +5,13 @@ import (
 	"os"
 	"path"
 
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/afifurrohman-id/tempsy/pkg/middleware"
 	"github.com/afifurrohman-id/tempsy/pkg/router"
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/etag"
 	"github.com/gofiber/fiber/v2/middleware/favicon"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -51,9 +51,7 @@ func main() {
 		EnableIPValidation: true,
 	})
 
-	app.Use(compress.New(compress.Config{
-		Level: compress.LevelBestCompression,
-	}), recover.New(), favicon.New(), logger.New(logger.Config{
+	app.Use(middleware.Compress, recover.New(), favicon.New(), logger.New(logger.Config{
 		Output:        multiWriter,
 		DisableColors: true,
 	}), middleware.Cors, middleware.CheckHttpMethod, swagger.New(swagger.Config{
@@ -74,16 +72,61 @@ func main() {
 		return ctx.SendStatus(fiber.StatusNoContent)
 	})
 
+	// end-to-end encrypted file download page, `web` is the client submodule
+	app.Static("/assets", "assets", fiber.Static{
+		Compress: true,
+	})
+
+	// share link, resolved through the server
+	app.Get("/s/:id", middleware.RateLimiterFilePassword, router.HandleGetShare)
+	app.Post("/s/:id", middleware.RateLimiterFilePassword, router.HandleUnlockShare)
+
 	routeAuthApi := app.Group("/auth")
 	routeAuthApi.Get("/userinfo/me", middleware.RateLimiterProcessing, etag.New(), router.HandleGetUserInfo)
 	routeAuthApi.Get("/guest/token", middleware.RateLimiterGuestToken, router.HandleGetGuestToken)
+	routeAuthApi.Post("/guest/claim", middleware.RateLimiterProcessing, router.HandleClaimGuest)
+	routeAuthApi.Post("/keys", middleware.Authenticate, middleware.RateLimiterProcessing, router.HandleCreateApiKey)
+	routeAuthApi.Get("/keys", middleware.Authenticate, middleware.RateLimiterProcessing, router.HandleListApiKeys)
+	routeAuthApi.Delete("/keys/:id", middleware.Authenticate, middleware.RateLimiterProcessing, router.HandleRevokeApiKey)
+
+	routeOrgs := app.Group("/orgs")
+	routeOrgs.Post("/", middleware.Authenticate, middleware.RateLimiterProcessing, router.HandleCreateOrg)
+	routeOrgs.Get("/:org", middleware.CheckOrgRole(store.OrgRoleReader), middleware.RateLimiterProcessing, router.HandleGetOrg)
+	routeOrgs.Put("/:org", middleware.CheckOrgRole(store.OrgRoleOwner), middleware.RateLimiterProcessing, router.HandleUpdateOrg)
+	routeOrgs.Delete("/:org", middleware.CheckOrgRole(store.OrgRoleOwner), middleware.RateLimiterProcessing, router.HandleDeleteOrg)
+	routeOrgs.Put("/:org/members/:username", middleware.CheckOrgRole(store.OrgRoleOwner), middleware.RateLimiterProcessing, router.HandlePutOrgMember)
+	routeOrgs.Delete("/:org/members/:username", middleware.CheckOrgRole(store.OrgRoleReader), middleware.RateLimiterProcessing, router.HandleRemoveOrgMember)
 
-	routeFilesByUsername := app.Group("/files/:username", middleware.PurgeAnonymousAccount, middleware.AutoDeleteScheduler)
-	routeFilesByUsername.Get("/public/:filename", middleware.Cache, router.HandleGetPublicFile)
+	routeFilesByUsername := app.Group("/files/:username", middleware.CheckFilesPath, middleware.PurgeAnonymousAccount, middleware.AutoDeleteScheduler)
+	routeFilesByUsername.Get("/public/archive", middleware.RateLimiterPublicArchive, router.HandleDownloadPublicArchive)
+	routeFilesByUsername.Get("/public/:filename", middleware.RateLimiterFilePassword, middleware.Cache, router.HandleGetPublicFile)
+	routeFilesByUsername.Post("/public/:filename", middleware.RateLimiterFilePassword, router.HandleUnlockPublicFile)
+	routeFilesByUsername.Get("/download/:filename", router.HandleDownloadFile)
 	routeFilesByUsername.Get("/", middleware.CheckAuth, middleware.RateLimiterProcessing, etag.New(), router.HandleListFilesData)
-	routeFilesByUsername.Get("/:filename", middleware.CheckAuth, middleware.RateLimiterProcessing, etag.New(), router.HandleGetFileData)
+	routeFilesByUsername.Get("/policy", middleware.CheckAuth, middleware.RateLimiterProcessing, etag.New(), router.HandleGetMimePolicy)
+	routeFilesByUsername.Get("/archive", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleDownloadArchive)
+	routeFilesByUsername.Get("/grants", middleware.CheckAuth, middleware.RateLimiterProcessing, etag.New(), router.HandleListReceivedGrants)
+	routeFilesByUsername.Get("/trash", middleware.CheckAuth, middleware.RateLimiterProcessing, etag.New(), router.HandleListTrash)
+	routeFilesByUsername.Post("/trash/:id/restore", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleRestoreTrash)
+	routeFilesByUsername.Delete("/trash", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleEmptyTrash)
+	routeFilesByUsername.Delete("/trash/:id", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandlePurgeTrash)
+	routeFilesByUsername.Get("/:filename", middleware.CheckFileAccess(store.GrantRoleRead), middleware.RateLimiterProcessing, etag.New(), router.HandleGetFileData)
+	routeFilesByUsername.Get("/:filename/versions", middleware.CheckFileAccess(store.GrantRoleRead), middleware.RateLimiterProcessing, etag.New(), router.HandleListVersions)
+	routeFilesByUsername.Get("/:filename/versions/:version", middleware.CheckFileAccess(store.GrantRoleRead), middleware.RateLimiterProcessing, router.HandleGetVersion)
+	routeFilesByUsername.Post("/:filename/versions/:version/restore", middleware.CheckFileAccess(store.GrantRoleWrite), middleware.RateLimiterProcessing, router.HandleRestoreVersion)
+	routeFilesByUsername.Post("/:filename/copy", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleCopyFile)
+	routeFilesByUsername.Post("/:filename/move", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleMoveFile)
+	routeFilesByUsername.Post("/:filename/transfer", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleTransferFile)
+	routeFilesByUsername.Get("/:filename/grants", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleListGrants)
+	routeFilesByUsername.Put("/:filename/grants/:grantee", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandlePutGrant)
+	routeFilesByUsername.Delete("/:filename/grants/:grantee", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleRevokeGrant)
+	routeFilesByUsername.Get("/:filename/shares", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleListShares)
+	routeFilesByUsername.Post("/:filename/shares", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleCreateShare)
+	routeFilesByUsername.Delete("/:filename/shares/:id", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleRevokeShare)
 	routeFilesByUsername.Post("/", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleUploadFile)
-	routeFilesByUsername.Put("/:filename", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleUpdateFile)
+	routeFilesByUsername.Post("/presign", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandlePresignUpload)
+	routeFilesByUsername.Post("/presign/:id", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleFinalizeUpload)
+	routeFilesByUsername.Put("/:filename", middleware.CheckFileAccess(store.GrantRoleWrite), middleware.RateLimiterProcessing, router.HandleUpdateFile)
 	routeFilesByUsername.Delete("/", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleDeleteAllFile)
 	routeFilesByUsername.Delete("/:filename", middleware.CheckAuth, middleware.RateLimiterProcessing, router.HandleDeleteFile)
 
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path"
+	"time"
+
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/joho/godotenv"
+)
+
+func init() {
+	if os.Getenv("APP_ENV") != "production" {
+		utils.LogErr(godotenv.Load(path.Join("configs", ".env")))
+	}
+}
+
+// Re-encrypt job, rewrap data key of every object with active master key after key rotation
+// and encrypt object that uploaded before encryption is enabled
+func main() {
+	var (
+		prefix  = flag.String("prefix", "", "Only re-encrypt object with this prefix (e.g. username)")
+		timeout = flag.Duration("timeout", 30*time.Minute, "Maximum duration of the job")
+	)
+	flag.Parse()
+
+	if !store.EncryptionEnabled() {
+		log.Fatal("STORAGE_ENCRYPTION_KEYS is not configured")
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	total, err := store.ReEncryptObjects(storeCtx, *prefix)
+	log.Infof("Re-encrypted %d objects", total)
+	utils.Check(err)
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const UsernamePrefix = "tempsyanonym-"
+const (
+	UsernamePrefix = "tempsyanonym-"
+	TokenLifetime  = 168 * time.Hour // 7 days
+)
 
 // GenerateUsername
 // Format: tempsyanonym-<unix-milli-expired-in-7-days>-<random-string>
@@ -32,7 +35,7 @@ func CreateToken(username string) (string, error) {
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
 		Subject:   "guest",
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(168 * time.Hour)), // 7 days
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenLifetime)),
 		ID:        username,
 	})
 
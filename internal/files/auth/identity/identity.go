@@ -0,0 +1,112 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/oauth2"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalKey key of authenticated Identity in fiber locals
+const LocalKey = "identity"
+
+var (
+	ErrorInvalidToken = errors.New("invalid_token")
+	ErrorRevokedToken = errors.New("token_is_revoked")
+	// ErrorUnavailable token cannot be verified because storage is unavailable, it's not mean the token is invalid
+	ErrorUnavailable = errors.New("identity_service_unavailable")
+
+	// isTokenRevoked is replaced in test, so guest token can be verified without storage
+	isTokenRevoked = store.IsTokenRevoked
+	// verifyIdToken and introspectAccessToken is replaced in test, so Google token can be verified without Google
+	verifyIdToken         = oauth2.VerifyIdToken
+	introspectAccessToken = oauth2.IntrospectAccessToken
+)
+
+// Identity user that own the token
+type Identity struct {
+	UserName string
+	Guest    bool
+	ApiKey   *store.ApiKeyRecord // not nil when user is authenticated by api key, request is limited by its scopes
+}
+
+// Identify return the user of bearer token, api key and guest token must not be revoked or expired,
+// otherwise it's Google ID token or access token and the email must be verified
+func Identify(authorization string) (*Identity, error) {
+	if !strings.HasPrefix(authorization, auth.BearerPrefix) {
+		return nil, ErrorInvalidToken
+	}
+	token := strings.TrimPrefix(authorization, auth.BearerPrefix)
+
+	if strings.HasPrefix(token, store.ApiKeyPrefix) {
+		storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+		defer cancel()
+
+		record, err := store.VerifyApiKey(storeCtx, token)
+		if err != nil {
+			if errors.Is(err, store.ErrorInvalidApiKey) {
+				return nil, errors.Join(ErrorInvalidToken, err)
+			}
+			return nil, errors.Join(ErrorUnavailable, err)
+		}
+
+		return &Identity{UserName: record.Owner, ApiKey: record}, nil
+	}
+
+	if claims, err := guest.ParseToken(token); err == nil {
+		username, ok := claims["jti"].(string)
+		if !ok || !strings.HasPrefix(username, guest.UsernamePrefix) {
+			return nil, ErrorInvalidToken
+		}
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+		defer cancel()
+
+		// guest account is claimed by other account
+		revoked, err := isTokenRevoked(storeCtx, username)
+		if err != nil {
+			return nil, errors.Join(ErrorUnavailable, err)
+		}
+		if revoked {
+			return nil, ErrorRevokedToken
+		}
+
+		return &Identity{UserName: username, Guest: true}, nil
+	}
+
+	// ID token is JWT that is verified locally, access token is opaque so it's introspected by Google and cached
+	var (
+		accountInfo *models.GoogleAccountInfo
+		err         error
+	)
+	if strings.Count(token, ".") == 2 {
+		accountInfo, err = verifyIdToken(token)
+	} else {
+		accountInfo, err = introspectAccessToken(token)
+	}
+	if err != nil {
+		// Google cannot be reached, the token is not rejected
+		if errors.Is(err, oauth2.ErrorGOAuth2Unavailable) {
+			return nil, errors.Join(ErrorUnavailable, err)
+		}
+		return nil, errors.Join(ErrorInvalidToken, err)
+	}
+
+	if !accountInfo.VerifiedEmail {
+		return nil, ErrorInvalidToken
+	}
+
+	return &Identity{UserName: accountInfo.UserName}, nil
+}
+
+// FromCtx return Identity that is authenticated by middleware, nil when request is not authenticated
+func FromCtx(ctx *fiber.Ctx) *Identity {
+	user, _ := ctx.Locals(LocalKey).(*Identity)
+	return user
+}
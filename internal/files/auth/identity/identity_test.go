@@ -0,0 +1,123 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/oauth2"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentify(test *testing.T) {
+	test.Run("TestOkGuest", func(test *testing.T) {
+		stubTokenRevoked(test, false, nil)
+
+		username := guest.GenerateUsername()
+		token, err := guest.CreateToken(username)
+		require.NoError(test, err)
+
+		user, err := Identify(auth.BearerPrefix + token)
+		require.NoError(test, err)
+
+		assert.Equal(test, username, user.UserName)
+		assert.True(test, user.Guest)
+	})
+
+	test.Run("TestOnRevokedGuest", func(test *testing.T) {
+		stubTokenRevoked(test, true, nil)
+
+		token, err := guest.CreateToken(guest.GenerateUsername())
+		require.NoError(test, err)
+
+		user, err := Identify(auth.BearerPrefix + token)
+		assert.ErrorIs(test, err, ErrorRevokedToken)
+		assert.Nil(test, user)
+	})
+
+	test.Run("TestOnStorageUnavailable", func(test *testing.T) {
+		stubTokenRevoked(test, false, context.DeadlineExceeded)
+
+		token, err := guest.CreateToken(guest.GenerateUsername())
+		require.NoError(test, err)
+
+		user, err := Identify(auth.BearerPrefix + token)
+		assert.ErrorIs(test, err, ErrorUnavailable)
+		assert.NotErrorIs(test, err, ErrorInvalidToken)
+		assert.Nil(test, user)
+	})
+
+	test.Run("TestOnInvalidApiKey", func(test *testing.T) {
+		user, err := Identify(auth.BearerPrefix + store.ApiKeyPrefix + "missing-secret")
+		assert.ErrorIs(test, err, ErrorInvalidToken)
+		assert.ErrorIs(test, err, store.ErrorInvalidApiKey)
+		assert.Nil(test, user)
+	})
+
+	test.Run("TestOnGoogleUnavailable", func(test *testing.T) {
+		stubGoogle(test, nil, errors.Join(oauth2.ErrorGOAuth2Unavailable, errors.New("dial_tcp_timeout")))
+
+		for _, token := range []string{"header.payload.signature", "access-token"} {
+			user, err := Identify(auth.BearerPrefix + token)
+			assert.ErrorIs(test, err, ErrorUnavailable)
+			assert.NotErrorIs(test, err, ErrorInvalidToken)
+			assert.Nil(test, user)
+		}
+	})
+
+	test.Run("TestOnRejectedGoogleToken", func(test *testing.T) {
+		stubGoogle(test, nil, oauth2.ErrorGOAuth2)
+
+		user, err := Identify(auth.BearerPrefix + "access-token")
+		assert.ErrorIs(test, err, ErrorInvalidToken)
+		assert.NotErrorIs(test, err, ErrorUnavailable)
+		assert.Nil(test, user)
+	})
+
+	test.Run("TestOkGoogle", func(test *testing.T) {
+		stubGoogle(test, &models.GoogleAccountInfo{User: &models.User{UserName: "john-doe-gmail-com"}, VerifiedEmail: true}, nil)
+
+		user, err := Identify(auth.BearerPrefix + "access-token")
+		require.NoError(test, err)
+		assert.Equal(test, "john-doe-gmail-com", user.UserName)
+	})
+
+	test.Run("TestOnMissingBearer", func(test *testing.T) {
+		token, err := guest.CreateToken(guest.GenerateUsername())
+		require.NoError(test, err)
+
+		user, err := Identify(token)
+		assert.ErrorIs(test, err, ErrorInvalidToken)
+		assert.Nil(test, user)
+	})
+}
+
+// stubTokenRevoked replace revocation check of storage until the test is finished
+func stubTokenRevoked(test *testing.T, revoked bool, err error) {
+	test.Cleanup(func() {
+		isTokenRevoked = store.IsTokenRevoked
+	})
+
+	isTokenRevoked = func(context.Context, string) (bool, error) {
+		return revoked, err
+	}
+}
+
+// stubGoogle replace verification of Google ID token and access token until the test is finished
+func stubGoogle(test *testing.T, accountInfo *models.GoogleAccountInfo, err error) {
+	test.Cleanup(func() {
+		verifyIdToken = oauth2.VerifyIdToken
+		introspectAccessToken = oauth2.IntrospectAccessToken
+	})
+
+	verify := func(string) (*models.GoogleAccountInfo, error) {
+		return accountInfo, err
+	}
+	verifyIdToken = verify
+	introspectAccessToken = verify
+}
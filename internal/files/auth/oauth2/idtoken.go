@@ -0,0 +1,201 @@
+package oauth2
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	GoogleJwksUrl = "https://www.googleapis.com/oauth2/v3/certs"
+	// JwksCacheTTL how long signing keys of Google is used before it's fetched again,
+	// new key is published before it's used, and unknown key id is fetched immediately
+	JwksCacheTTL = 1 * time.Hour
+	// JwksMinRefreshInterval prevent token with random key id from fetching the keys on every request
+	JwksMinRefreshInterval = 1 * time.Minute
+)
+
+var (
+	ErrorUnknownSigningKey = errors.New("id_token_signing_key_is_unknown")
+	ErrorInvalidIdToken    = errors.New("id_token_issuer_or_audience_is_not_valid")
+
+	googleIssuers = []string{"accounts.google.com", "https://accounts.google.com"}
+	googleKeys    = new(keySet)
+
+	// fetchKeys is replaced in test, so failed fetch can be checked without Google
+	fetchKeys = fetchGoogleKeys
+)
+
+type googleIdClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Picture       string `json:"picture"`
+}
+
+// keySet cached public keys of JWKS by key id
+type keySet struct {
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	// only one request fetch the keys, and failed fetch is not retried by every request when Google cannot be reached
+	refreshMu   sync.Mutex
+	attemptedAt time.Time
+	attemptErr  error // error of the last fetch, returned until the next fetch is allowed
+}
+
+// VerifyIdToken verify Google ID token locally with cached signing keys of Google,
+// audience must be one of client id in env `GOOGLE_OAUTH2_CLIENT_IDS` (comma separated).
+// Error is ErrorGOAuth2Unavailable when signing keys cannot be fetched, the token is not rejected
+func VerifyIdToken(idToken string) (*models.GoogleAccountInfo, error) {
+	claims := new(googleIdClaims)
+
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return googleKeys.key(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(googleIssuers, claims.Issuer) || !validAudience(claims.Audience) {
+		return nil, ErrorInvalidIdToken
+	}
+
+	return &models.GoogleAccountInfo{
+		User:          &models.User{UserName: userNameOf(claims.Email)},
+		Email:         claims.Email,
+		Picture:       claims.Picture,
+		ID:            claims.Subject,
+		VerifiedEmail: claims.EmailVerified,
+	}, nil
+}
+
+func validAudience(audience jwt.ClaimStrings) bool {
+	for _, clientId := range strings.Split(os.Getenv("GOOGLE_OAUTH2_CLIENT_IDS"), ",") {
+		if clientId = strings.TrimSpace(clientId); clientId != "" && slices.Contains(audience, clientId) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// key return public key by key id, keys is fetched again when it's expired or key id is unknown.
+// Cached key is still used when Google cannot be reached
+func (set *keySet) key(kid string) (*rsa.PublicKey, error) {
+	set.mu.RLock()
+	key, ok := set.keys[kid]
+	fresh := time.Since(set.fetchedAt) < JwksCacheTTL
+	set.mu.RUnlock()
+
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := set.refresh(); err != nil {
+		if ok {
+			log.Error(err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	set.mu.RLock()
+	key, ok = set.keys[kid]
+	set.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrorUnknownSigningKey
+	}
+
+	return key, nil
+}
+
+func (set *keySet) refresh() error {
+	set.refreshMu.Lock()
+	defer set.refreshMu.Unlock()
+
+	// fetched by other request while waiting
+	if time.Since(set.attemptedAt) < JwksMinRefreshInterval {
+		return set.attemptErr
+	}
+	set.attemptedAt = time.Now()
+
+	keys, err := fetchKeys()
+	if err != nil {
+		if !errors.Is(err, ErrorGOAuth2Unavailable) {
+			err = errors.Join(ErrorGOAuth2Unavailable, err)
+		}
+		set.attemptErr = err
+		return err
+	}
+	set.attemptErr = nil
+
+	set.mu.Lock()
+	set.keys = keys
+	set.fetchedAt = time.Now()
+	set.mu.Unlock()
+
+	return nil
+}
+
+func fetchGoogleKeys() (map[string]*rsa.PublicKey, error) {
+	agent := fiber.Get(GoogleJwksUrl)
+	agent.Timeout(10 * time.Second)
+
+	jwks := new(struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	})
+
+	statusCode, body, errs := agent.Struct(jwks)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	if statusCode != fiber.StatusOK {
+		log.Errorf("jwks_response_from_%d_body_%s", statusCode, string(body))
+		return nil, ErrorGOAuth2Unavailable
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks_key_%s_invalid_modulus: %w", jwk.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwks_key_%s_invalid_exponent: %w", jwk.Kid, err)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,158 @@
+package oauth2
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIdToken(test *testing.T) {
+	const (
+		kid      = "test-kid"
+		clientId = "test-client-id.apps.googleusercontent.com"
+	)
+
+	test.Setenv("GOOGLE_OAUTH2_CLIENT_IDS", "other-client-id, "+clientId)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(test, err)
+
+	// keys is already fetched, so the test is not reach Google
+	googleKeys.mu.Lock()
+	googleKeys.keys = map[string]*rsa.PublicKey{kid: &privateKey.PublicKey}
+	googleKeys.fetchedAt = time.Now()
+	googleKeys.attemptedAt = time.Now()
+	googleKeys.mu.Unlock()
+
+	test.Cleanup(func() {
+		googleKeys.mu.Lock()
+		defer googleKeys.mu.Unlock()
+
+		googleKeys.keys = nil
+		googleKeys.fetchedAt = time.Time{}
+		googleKeys.attemptedAt = time.Time{}
+	})
+
+	createIdToken := func(test *testing.T, method jwt.SigningMethod, key any, kid string, update func(claims *googleIdClaims)) string {
+		claims := &googleIdClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "https://accounts.google.com",
+				Subject:   "1234567890",
+				Audience:  jwt.ClaimStrings{clientId},
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
+			},
+			Email:         "john.doe@gmail.com",
+			EmailVerified: true,
+		}
+		if update != nil {
+			update(claims)
+		}
+
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+
+		signed, err := token.SignedString(key)
+		require.NoError(test, err)
+
+		return signed
+	}
+
+	test.Run("TestOk", func(test *testing.T) {
+		accountInfo, err := VerifyIdToken(createIdToken(test, jwt.SigningMethodRS256, privateKey, kid, nil))
+		require.NoError(test, err)
+
+		assert.Equal(test, "john-doe-gmail-com", accountInfo.UserName)
+		assert.Equal(test, "1234567890", accountInfo.ID)
+		assert.True(test, accountInfo.VerifiedEmail)
+	})
+
+	tableTests := []struct {
+		name   string
+		method jwt.SigningMethod
+		key    any
+		kid    string
+		update func(claims *googleIdClaims)
+		err    error
+	}{
+		{
+			name:   "TestOnOtherAudience",
+			method: jwt.SigningMethodRS256,
+			key:    privateKey,
+			kid:    kid,
+			update: func(claims *googleIdClaims) {
+				claims.Audience = jwt.ClaimStrings{"unknown-client-id"}
+			},
+			err: ErrorInvalidIdToken,
+		},
+		{
+			name:   "TestOnOtherIssuer",
+			method: jwt.SigningMethodRS256,
+			key:    privateKey,
+			kid:    kid,
+			update: func(claims *googleIdClaims) {
+				claims.Issuer = "https://example.com"
+			},
+			err: ErrorInvalidIdToken,
+		},
+		{
+			name:   "TestOnExpired",
+			method: jwt.SigningMethodRS256,
+			key:    privateKey,
+			kid:    kid,
+			update: func(claims *googleIdClaims) {
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-1 * time.Minute))
+			},
+			err: jwt.ErrTokenExpired,
+		},
+		{
+			name:   "TestOnUnknownKey",
+			method: jwt.SigningMethodRS256,
+			key:    privateKey,
+			kid:    "unknown-kid",
+			err:    ErrorUnknownSigningKey,
+		},
+		{
+			name:   "TestOnHmac",
+			method: jwt.SigningMethodHS256,
+			key:    []byte("test-secret"),
+			kid:    kid,
+			err:    jwt.ErrTokenSignatureInvalid,
+		},
+	}
+
+	for _, tableTest := range tableTests {
+		test.Run(tableTest.name, func(test *testing.T) {
+			accountInfo, err := VerifyIdToken(createIdToken(test, tableTest.method, tableTest.key, tableTest.kid, tableTest.update))
+			assert.ErrorIs(test, err, tableTest.err)
+			assert.Nil(test, accountInfo)
+		})
+	}
+	test.Run("TestOnKeysUnavailable", func(test *testing.T) {
+		googleKeys.mu.Lock()
+		googleKeys.keys = nil
+		googleKeys.attemptedAt = time.Time{}
+		googleKeys.mu.Unlock()
+
+		fetchKeys = func() (map[string]*rsa.PublicKey, error) {
+			return nil, errors.New("dial_tcp_timeout")
+		}
+		test.Cleanup(func() {
+			fetchKeys = fetchGoogleKeys
+		})
+
+		// the next request before fetch is allowed again is still unavailable, not unknown key
+		for i := 0; i < 2; i++ {
+			accountInfo, err := VerifyIdToken(createIdToken(test, jwt.SigningMethodRS256, privateKey, kid, nil))
+			assert.ErrorIs(test, err, ErrorGOAuth2Unavailable)
+			assert.NotErrorIs(test, err, ErrorUnknownSigningKey)
+			assert.Nil(test, accountInfo)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// AccountInfoCacheTTL how long account info of access token is trusted without asking Google again
+	AccountInfoCacheTTL = 5 * time.Minute
+	// AccountInfoStaleTTL how long cached account info is still used when Google cannot be reached,
+	// the same as lifetime of Google access token
+	AccountInfoStaleTTL = 1 * time.Hour
+)
+
+type accountInfoEntry struct {
+	accountInfo *models.GoogleAccountInfo
+	checkedAt   time.Time
+}
+
+var (
+	// accountInfoCache keyed by hash of access token, so the token is not kept in memory
+	accountInfoCache sync.Map
+	lastSweepAt      atomic.Int64
+)
+
+// IntrospectAccessToken return account info of access token from cache, or from Google when it's not cached
+// or older than AccountInfoCacheTTL. Token that is rejected by Google is removed from cache immediately,
+// error is ErrorGOAuth2Unavailable when Google cannot be reached and the token is not cached
+func IntrospectAccessToken(accessToken string) (*models.GoogleAccountInfo, error) {
+	sum := sha256.Sum256([]byte(accessToken))
+	key := hex.EncodeToString(sum[:])
+
+	value, cached := accountInfoCache.Load(key)
+	if cached && time.Since(value.(*accountInfoEntry).checkedAt) < AccountInfoCacheTTL {
+		return value.(*accountInfoEntry).accountInfo, nil
+	}
+
+	accountInfo, err := GetGoogleAccountInfo(accessToken)
+	if err != nil {
+		if errors.Is(err, ErrorGOAuth2) {
+			accountInfoCache.Delete(key)
+			return nil, err
+		}
+
+		// Google is not reachable, token that is verified recently is still trusted
+		if cached && time.Since(value.(*accountInfoEntry).checkedAt) < AccountInfoStaleTTL {
+			log.Error(err)
+			return value.(*accountInfoEntry).accountInfo, nil
+		}
+		return nil, err
+	}
+
+	accountInfoCache.Store(key, &accountInfoEntry{accountInfo: accountInfo, checkedAt: time.Now()})
+	sweepAccountInfoCache()
+
+	return accountInfo, nil
+}
+
+// sweepAccountInfoCache remove expired access token, at most once per AccountInfoStaleTTL
+func sweepAccountInfoCache() {
+	var (
+		now  = time.Now()
+		last = lastSweepAt.Load()
+	)
+
+	if now.Sub(time.Unix(0, last)) < AccountInfoStaleTTL || !lastSweepAt.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+
+	accountInfoCache.Range(func(key, value any) bool {
+		if now.Sub(value.(*accountInfoEntry).checkedAt) >= AccountInfoStaleTTL {
+			accountInfoCache.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,43 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectAccessToken(test *testing.T) {
+	const accessToken = "ya29.test-introspect-access-token"
+
+	sum := sha256.Sum256([]byte(accessToken))
+	key := hex.EncodeToString(sum[:])
+
+	test.Cleanup(func() {
+		accountInfoCache.Delete(key)
+	})
+
+	test.Run("TestOkCached", func(test *testing.T) {
+		cached := &models.GoogleAccountInfo{User: &models.User{UserName: "john-doe-gmail-com"}, VerifiedEmail: true}
+		accountInfoCache.Store(key, &accountInfoEntry{accountInfo: cached, checkedAt: time.Now()})
+
+		// cached token is not sent to Google
+		accountInfo, err := IntrospectAccessToken(accessToken)
+		require.NoError(test, err)
+		assert.Same(test, cached, accountInfo)
+	})
+
+	test.Run("TestOkSweep", func(test *testing.T) {
+		accountInfoCache.Store(key, &accountInfoEntry{checkedAt: time.Now().Add(-AccountInfoStaleTTL)})
+		lastSweepAt.Store(0)
+
+		sweepAccountInfoCache()
+
+		_, ok := accountInfoCache.Load(key)
+		assert.False(test, ok)
+	})
+}
@@ -13,7 +13,11 @@ import (
 	"github.com/gofiber/fiber/v2/log"
 )
 
-var ErrorGOAuth2 = errors.New("oauth2_error_response_code_not_ok")
+var (
+	ErrorGOAuth2 = errors.New("oauth2_error_response_code_not_ok")
+	// ErrorGOAuth2Unavailable server error of Google, the token is not rejected
+	ErrorGOAuth2Unavailable = errors.New("oauth2_server_unavailable")
+)
 
 func GetAccessToken(refreshToken string) (*models.GOAuth2Token, error) {
 	payloadFormUri := fmt.Sprintf("client_secret=%s&grant_type=refresh_token&refresh_token=%s&client_id=%s", os.Getenv("GOOGLE_OAUTH2_CLIENT_SECRET_TEST"), refreshToken, os.Getenv("GOOGLE_OAUTH2_CLIENT_ID_TEST"))
@@ -47,7 +51,13 @@ func GetGoogleAccountInfo(accessToken string) (*models.GoogleAccountInfo, error)
 
 	statusCode, body, errs := agent.Struct(&userinfo)
 	if len(errs) > 0 {
-		return nil, errs[0]
+		// Google cannot be reached, the token is not rejected
+		return nil, errors.Join(ErrorGOAuth2Unavailable, errs[0])
+	}
+
+	if statusCode >= fiber.StatusInternalServerError {
+		log.Errorf("response_from_%d_body_%s", statusCode, string(body))
+		return nil, ErrorGOAuth2Unavailable
 	}
 
 	if statusCode != fiber.StatusOK {
@@ -56,8 +66,13 @@ func GetGoogleAccountInfo(accessToken string) (*models.GoogleAccountInfo, error)
 	}
 
 	userinfo.User = &models.User{
-		UserName: strings.ReplaceAll(strings.Join(strings.SplitN(userinfo.Email, "@", 2), "-"), ".", "-"),
+		UserName: userNameOf(userinfo.Email),
 	}
 
 	return userinfo, nil
 }
+
+// userNameOf return username of Google account, e.g. `john.doe@gmail.com` become `john-doe-gmail-com`
+func userNameOf(email string) string {
+	return strings.ReplaceAll(strings.Join(strings.SplitN(email, "@", 2), "-"), ".", "-")
+}
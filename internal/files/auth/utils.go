@@ -8,6 +8,10 @@ var AllowedHttpMethod = []string{fiber.MethodGet, fiber.MethodDelete, fiber.Meth
 
 const (
 	BearerPrefix = "Bearer "
+	// HeaderTransferAuthorization bearer token of the new owner, when file is transferred to other user
+	HeaderTransferAuthorization = "transfer-authorization"
+	// HeaderGuestAuthorization bearer token of guest account, when guest account is claimed
+	HeaderGuestAuthorization = "guest-authorization"
 	// follow HTTP 2.0 (lowercase), but still backward compatible with HTTP 1.1 because it's case insensitive
 	HeaderRealIp  = "real-IP"
 	HeaderXRealIp = "x-real-ip" // Backward compatibility purpose
@@ -0,0 +1,12 @@
+package models
+
+// ApiKey long-lived key of user for non-human client, e.g. CI job
+type ApiKey struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"` // secret key, only returned once when it's created
+	Scopes     []string `json:"scopes"`        // read, upload, delete or public-toggle
+	PathPrefix string   `json:"pathPrefix,omitempty"`
+	ExpiresAt  int64    `json:"expiresAt"` // in milliseconds, 0 is never expired
+	CreatedAt  int64    `json:"createdAt"` // in milliseconds
+}
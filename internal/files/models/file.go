@@ -1,13 +1,75 @@
 package models
 
 type DataFile struct {
-	Name              string `json:"name"`
-	Url               string `json:"url"`
-	MimeType          string `json:"mimeType"`
-	AutoDeleteAt      int64  `json:"autoDeleteAt"`      // in milliseconds
-	PrivateUrlExpires uint   `json:"privateUrlExpires"` // in seconds
-	UploadedAt        int64  `json:"uploadedAt"`        // in milliseconds
-	UpdatedAt         int64  `json:"updatedAt"`         // in milliseconds
-	Size              int64  `json:"size"`              // in bytes
-	IsPublic          bool   `json:"isPublic"`
+	Name                string `json:"name"`
+	Url                 string `json:"url"`
+	MimeType            string `json:"mimeType"`
+	AutoDeleteAt        int64  `json:"autoDeleteAt"`      // in milliseconds
+	PrivateUrlExpires   uint   `json:"privateUrlExpires"` // in seconds
+	UploadedAt          int64  `json:"uploadedAt"`        // in milliseconds
+	UpdatedAt           int64  `json:"updatedAt"`         // in milliseconds
+	Size                int64  `json:"size"`              // in bytes
+	IsPublic            bool   `json:"isPublic"`
+	Sha256              string `json:"sha256,omitempty"`    // hex, only for deduplicated file
+	Crc32c              string `json:"crc32c,omitempty"`    // base64 big-endian, same format as storage
+	E2eHeader           string `json:"e2eHeader,omitempty"` // opaque encryption header, only for end-to-end encrypted file
+	IsPasswordProtected bool   `json:"isPasswordProtected"`
+	MaxDownloads        uint   `json:"maxDownloads"` // 0 is unlimited
+	DownloadCount       uint   `json:"downloadCount"`
+	MaxVersions         int    `json:"maxVersions"` // 0 is no history, -1 is unlimited
+	FileId              string `json:"-"`           // random id, it's changed when file is deleted and uploaded again
+	PasswordHash        string `json:"-"`
+}
+
+// PresignedUpload url to upload file directly to the storage
+type PresignedUpload struct {
+	Id        string            `json:"id"`
+	Url       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`   // must be sent with the same value
+	ExpiresAt int64             `json:"expiresAt"` // in milliseconds
+}
+
+// FileVersion previous content of file
+type FileVersion struct {
+	Id        string `json:"id"`
+	MimeType  string `json:"mimeType"`
+	Size      int64  `json:"size"` // in bytes
+	Sha256    string `json:"sha256"`
+	E2eHeader string `json:"e2eHeader,omitempty"`
+	CreatedAt int64  `json:"createdAt"` // in milliseconds, when the content is uploaded
+}
+
+const (
+	FileResultCreated     = "created"
+	FileResultDeleted     = "deleted"
+	FileResultDryRun      = "dry_run" // will be deleted, if it's not dry run
+	FileResultNotFound    = "not_found"
+	FileResultFailed      = "failed"
+	FileResultTransferred = "transferred"
+)
+
+// FileResult outcome of bulk operation for each file
+type FileResult struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"` // only when file is renamed because of name conflict
+	Status  string `json:"status"`
+	Error   *Error `json:"error,omitempty"` // only when status is failed
+}
+
+// ExtractResult response of uploading archive with extract
+type ExtractResult struct {
+	Results []*FileResult `json:"results"`
+}
+
+// ClaimResult response of claiming guest files, guest token is revoked only when all files is transferred
+type ClaimResult struct {
+	Revoked bool          `json:"revoked"`
+	Results []*FileResult `json:"results"`
+}
+
+// BulkDeleteResult response of deleting many files at once
+type BulkDeleteResult struct {
+	DryRun  bool          `json:"dryRun"`
+	Results []*FileResult `json:"results"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// Grant access of other user to the file
+type Grant struct {
+	Owner     string `json:"owner"`
+	FileName  string `json:"fileName"`
+	Grantee   string `json:"grantee"`
+	Role      string `json:"role"`      // read or write
+	CreatedAt int64  `json:"createdAt"` // in milliseconds
+}
@@ -0,0 +1,18 @@
+package models
+
+// Org organization with shared namespace `org:<name>`
+type Org struct {
+	Name                string            `json:"name"`
+	Members             map[string]string `json:"members"`             // username to role (owner, writer or reader)
+	MaxFiles            int               `json:"maxFiles"`            // 0 is unlimited
+	MaxBytes            int64             `json:"maxBytes"`            // 0 is unlimited
+	DefaultAutoDeleteIn int64             `json:"defaultAutoDeleteIn"` // in milliseconds, auto delete of file that is uploaded without it, 0 is no default
+	CreatedAt           int64             `json:"createdAt"`           // in milliseconds
+	Usage               *OrgUsage         `json:"usage,omitempty"`
+}
+
+// OrgUsage storage that is used by files of organization, versions and trash is not counted
+type OrgUsage struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type Share struct {
+	Id                  string `json:"id"`
+	Url                 string `json:"url"`
+	FileName            string `json:"fileName"`
+	ExpiresAt           int64  `json:"expiresAt"`    // in milliseconds
+	MaxDownloads        uint   `json:"maxDownloads"` // 0 is unlimited
+	DownloadCount       uint   `json:"downloadCount"`
+	IsPasswordProtected bool   `json:"isPasswordProtected"`
+	CreatedAt           int64  `json:"createdAt"` // in milliseconds
+}
@@ -0,0 +1,10 @@
+package models
+
+type TrashEntry struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	MimeType  string `json:"mimeType"`
+	Size      int64  `json:"size"`      // in bytes
+	DeletedAt int64  `json:"deletedAt"` // in milliseconds
+	PurgeAt   int64  `json:"purgeAt"`   // in milliseconds, when it's deleted permanently
+}
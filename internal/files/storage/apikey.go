@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"golang.org/x/exp/slices"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// ApiKeyPrefix prefix of api key `tempsy_<id>.<secret>`, so it's never confused with JWT or Google token
+	ApiKeyPrefix        = "tempsy_"
+	ApiKeyScopeRead     = "read"
+	ApiKeyScopeUpload   = "upload"
+	ApiKeyScopeDelete   = "delete"
+	ApiKeyScopePublic   = "public-toggle"
+	MaxApiKeyNameLength = 64
+	MaxApiKeysPerUser   = 20
+	// ApiKeyCacheTTL how long verified api key is trusted without reading the record,
+	// key that is revoked by other instance is still accepted until then
+	ApiKeyCacheTTL      = 1 * time.Minute
+	metadataApiKeyOwner = "apikey-owner"
+	apiKeyRecordPrefix  = "apikeys/"
+	apiKeyIdLength      = 12
+	apiKeySecretLength  = 32
+)
+
+var (
+	ErrorApiKeyNotFound      = errors.New("api_key_not_found")
+	ErrorInvalidApiKey       = errors.New("api_key_is_invalid_expired_or_revoked")
+	ErrorInvalidApiKeyName   = errors.New("api_key_name_must_be_between_1_and_64_characters")
+	ErrorInvalidApiKeyScope  = errors.New("api_key_scopes_must_be_read_upload_delete_or_public_toggle")
+	ErrorInvalidApiKeyExpiry = errors.New("api_key_expires_at_must_be_in_the_future")
+	ErrorInvalidApiKeyPrefix = errors.New("api_key_path_prefix_cannot_contain_control_characters_or_path_separator")
+	ErrorTooManyApiKeys      = errors.New("too_many_api_keys_revoke_unused_key_first")
+
+	ApiKeyScopes = []string{ApiKeyScopeRead, ApiKeyScopeUpload, ApiKeyScopeDelete, ApiKeyScopePublic}
+
+	apiKeyCache sync.Map
+)
+
+// ApiKeyRecord api key of user, only hash of the secret is stored as record `apikeys/<id>`
+type ApiKeyRecord struct {
+	Id         string   `json:"id"`
+	Owner      string   `json:"owner"`
+	Name       string   `json:"name"`
+	SecretHash string   `json:"secretHash"` // sha256 in hex, secret is random so it's not need slow hash like password
+	Scopes     []string `json:"scopes"`
+	PathPrefix string   `json:"pathPrefix,omitempty"`
+	ExpiresAt  int64    `json:"expiresAt"` // in milliseconds, 0 is never expired
+	CreatedAt  int64    `json:"createdAt"` // in milliseconds
+}
+
+type apiKeyEntry struct {
+	record    *ApiKeyRecord
+	checkedAt time.Time
+}
+
+func (record *ApiKeyRecord) ApiKey() *models.ApiKey {
+	return &models.ApiKey{
+		Id:         record.Id,
+		Name:       record.Name,
+		Scopes:     record.Scopes,
+		PathPrefix: record.PathPrefix,
+		ExpiresAt:  record.ExpiresAt,
+		CreatedAt:  record.CreatedAt,
+	}
+}
+
+func (record *ApiKeyRecord) HasScope(scope string) bool {
+	return slices.Contains(record.Scopes, scope)
+}
+
+// AllowsFileName check file name is under path prefix of the key, key without path prefix allow all files
+func (record *ApiKeyRecord) AllowsFileName(fileName string) bool {
+	return strings.HasPrefix(fileName, record.PathPrefix)
+}
+
+func (record *ApiKeyRecord) expired() bool {
+	return record.ExpiresAt > 0 && record.ExpiresAt <= time.Now().UnixMilli()
+}
+
+// ValidateApiKey check name, scopes, path prefix and expiry of new api key
+func ValidateApiKey(apiKey *models.ApiKey) error {
+	if name := strings.TrimSpace(apiKey.Name); name == "" || len(name) > MaxApiKeyNameLength {
+		return ErrorInvalidApiKeyName
+	}
+
+	if len(apiKey.Scopes) == 0 {
+		return ErrorInvalidApiKeyScope
+	}
+	for _, scope := range apiKey.Scopes {
+		if !slices.Contains(ApiKeyScopes, scope) {
+			return ErrorInvalidApiKeyScope
+		}
+	}
+
+	// prefix is beginning of file name, e.g. `reports_`, so it's follow the same rule except extension
+	if len(apiKey.PathPrefix) > MaxFileNameLength || strings.ContainsAny(apiKey.PathPrefix, "/\\") || strings.IndexFunc(apiKey.PathPrefix, unicode.IsControl) >= 0 {
+		return ErrorInvalidApiKeyPrefix
+	}
+
+	if apiKey.ExpiresAt < 0 || (apiKey.ExpiresAt > 0 && apiKey.ExpiresAt <= time.Now().UnixMilli()) {
+		return ErrorInvalidApiKeyExpiry
+	}
+
+	return nil
+}
+
+// ApiKeyId return id of api key token without verify it, false when token is not api key
+func ApiKeyId(token string) (string, bool) {
+	if !strings.HasPrefix(token, ApiKeyPrefix) {
+		return "", false
+	}
+
+	id, _, ok := strings.Cut(strings.TrimPrefix(token, ApiKeyPrefix), ".")
+	if !ok || !validRandomId(id) {
+		return "", false
+	}
+
+	return id, true
+}
+
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateApiKey store new api key of owner, return the token that is only known by the owner
+func CreateApiKey(ctx context.Context, owner string, apiKey *models.ApiKey) (*ApiKeyRecord, string, error) {
+	records, err := ListApiKeys(ctx, owner)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(records) >= MaxApiKeysPerUser {
+		return nil, "", ErrorTooManyApiKeys
+	}
+
+	id, err := randomId(apiKeyIdLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomId(apiKeySecretLength)
+	if err != nil {
+		return nil, "", err
+	}
+
+	record := &ApiKeyRecord{
+		Id:         id,
+		Owner:      owner,
+		Name:       strings.TrimSpace(apiKey.Name),
+		SecretHash: hashApiKeySecret(secret),
+		Scopes:     apiKey.Scopes,
+		PathPrefix: norm.NFC.String(apiKey.PathPrefix), // the same as stored file name
+		ExpiresAt:  apiKey.ExpiresAt,
+		CreatedAt:  time.Now().UnixMilli(),
+	}
+
+	err = WriteRecord(ctx, apiKeyRecordPrefix+id, record, RecordMustNotExist, map[string]string{
+		metadataApiKeyOwner: owner,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return record, ApiKeyPrefix + id + "." + secret, nil
+}
+
+// VerifyApiKey return api key of the token, ErrorInvalidApiKey when it's not exist, expired or the secret is not match.
+// Record is cached for ApiKeyCacheTTL, since api key is used by every request of the client
+func VerifyApiKey(ctx context.Context, token string) (*ApiKeyRecord, error) {
+	id, ok := ApiKeyId(token)
+	if !ok {
+		return nil, ErrorInvalidApiKey
+	}
+	secret := token[len(ApiKeyPrefix)+len(id)+1:]
+
+	var record *ApiKeyRecord
+	if value, ok := apiKeyCache.Load(id); ok && time.Since(value.(*apiKeyEntry).checkedAt) < ApiKeyCacheTTL {
+		record = value.(*apiKeyEntry).record
+	} else {
+		record = new(ApiKeyRecord)
+		if _, err := ReadRecord(ctx, apiKeyRecordPrefix+id, record); err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				apiKeyCache.Delete(id)
+				return nil, ErrorInvalidApiKey
+			}
+			return nil, err
+		}
+
+		apiKeyCache.Store(id, &apiKeyEntry{record: record, checkedAt: time.Now()})
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashApiKeySecret(secret)), []byte(record.SecretHash)) != 1 || record.expired() {
+		return nil, ErrorInvalidApiKey
+	}
+
+	return record, nil
+}
+
+// ListApiKeys return all api key of owner, including expired key
+func ListApiKeys(ctx context.Context, owner string) ([]*ApiKeyRecord, error) {
+	names, err := ListRecords(ctx, apiKeyRecordPrefix, func(metadata map[string]string) bool {
+		return metadata[metadataApiKeyOwner] == owner
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ApiKeyRecord, 0, len(names))
+	for _, name := range names {
+		record := new(ApiKeyRecord)
+		if _, err = ReadRecord(ctx, name, record); err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				continue // revoked while listing
+			}
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// RevokeApiKey delete api key of owner, key of other user is the same as not found
+func RevokeApiKey(ctx context.Context, owner, id string) error {
+	if !validRandomId(id) {
+		return ErrorApiKeyNotFound
+	}
+
+	record := new(ApiKeyRecord)
+	if _, err := ReadRecord(ctx, apiKeyRecordPrefix+id, record); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorApiKeyNotFound
+		}
+		return err
+	}
+
+	if record.Owner != owner {
+		return ErrorApiKeyNotFound
+	}
+
+	if err := DeleteRecord(ctx, apiKeyRecordPrefix+id); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorApiKeyNotFound
+		}
+		return err
+	}
+
+	apiKeyCache.Delete(id)
+	return nil
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateApiKey(test *testing.T) {
+	tableTests := []struct {
+		name   string
+		apiKey *models.ApiKey
+		err    error
+	}{
+		{
+			name:   "TestOk",
+			apiKey: &models.ApiKey{Name: "ci", Scopes: []string{ApiKeyScopeRead, ApiKeyScopeUpload}, PathPrefix: "reports_"},
+		},
+		{
+			name:   "TestOnEmptyName",
+			apiKey: &models.ApiKey{Name: " ", Scopes: []string{ApiKeyScopeRead}},
+			err:    ErrorInvalidApiKeyName,
+		},
+		{
+			name:   "TestOnEmptyScopes",
+			apiKey: &models.ApiKey{Name: "ci"},
+			err:    ErrorInvalidApiKeyScope,
+		},
+		{
+			name:   "TestOnUnknownScope",
+			apiKey: &models.ApiKey{Name: "ci", Scopes: []string{"admin"}},
+			err:    ErrorInvalidApiKeyScope,
+		},
+		{
+			name:   "TestOnPathSeparator",
+			apiKey: &models.ApiKey{Name: "ci", Scopes: []string{ApiKeyScopeRead}, PathPrefix: "reports/"},
+			err:    ErrorInvalidApiKeyPrefix,
+		},
+		{
+			name:   "TestOnExpired",
+			apiKey: &models.ApiKey{Name: "ci", Scopes: []string{ApiKeyScopeRead}, ExpiresAt: time.Now().Add(-1 * time.Minute).UnixMilli()},
+			err:    ErrorInvalidApiKeyExpiry,
+		},
+	}
+
+	for _, tableTest := range tableTests {
+		test.Run(tableTest.name, func(test *testing.T) {
+			assert.ErrorIs(test, ValidateApiKey(tableTest.apiKey), tableTest.err)
+		})
+	}
+}
+
+func TestApiKeyId(test *testing.T) {
+	id, ok := ApiKeyId(ApiKeyPrefix + "ab-_cd.secret")
+	assert.True(test, ok)
+	assert.Equal(test, "ab-_cd", id)
+
+	_, ok = ApiKeyId(ApiKeyPrefix + "missing-secret")
+	assert.False(test, ok)
+
+	_, ok = ApiKeyId("eyJhbGciOiJIUzI1NiJ9.e30.signature")
+	assert.False(test, ok)
+}
+
+func TestApiKey(test *testing.T) {
+	const owner = "testapikey"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+	test.Cleanup(cancel)
+
+	record, token, err := CreateApiKey(storeCtx, owner, &models.ApiKey{Name: "ci", Scopes: []string{ApiKeyScopeRead}})
+	require.NoError(test, err)
+	require.True(test, strings.HasPrefix(token, ApiKeyPrefix+record.Id+"."))
+
+	test.Run("TestOkVerify", func(test *testing.T) {
+		verified, err := VerifyApiKey(storeCtx, token)
+		require.NoError(test, err)
+
+		assert.Equal(test, owner, verified.Owner)
+		assert.True(test, verified.HasScope(ApiKeyScopeRead))
+		assert.False(test, verified.HasScope(ApiKeyScopeDelete))
+	})
+
+	test.Run("TestOnWrongSecret", func(test *testing.T) {
+		_, err := VerifyApiKey(storeCtx, ApiKeyPrefix+record.Id+".wrong")
+		assert.ErrorIs(test, err, ErrorInvalidApiKey)
+	})
+
+	test.Run("TestOkList", func(test *testing.T) {
+		records, err := ListApiKeys(storeCtx, owner)
+		require.NoError(test, err)
+
+		require.Len(test, records, 1)
+		assert.Equal(test, record.Id, records[0].Id)
+	})
+
+	test.Run("TestOnRevokeOtherOwner", func(test *testing.T) {
+		assert.ErrorIs(test, RevokeApiKey(storeCtx, "other", record.Id), ErrorApiKeyNotFound)
+	})
+
+	test.Run("TestOkRevoke", func(test *testing.T) {
+		require.NoError(test, RevokeApiKey(storeCtx, owner, record.Id))
+
+		_, err := VerifyApiKey(storeCtx, token)
+		assert.ErrorIs(test, err, ErrorInvalidApiKey)
+	})
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+)
+
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+	// ArchiveTimeout maximum time to stream archive, it's longer than DefaultTimeoutCtx since archive can be large
+	ArchiveTimeout = 10 * time.Minute
+)
+
+var ErrorInvalidArchiveFormat = errors.New("archive_format_must_be_zip_or_tar.gz")
+
+// ArchiveContentType return content type of archive format, empty format is zip
+func ArchiveContentType(format string) (string, error) {
+	switch format {
+	case "", ArchiveFormatZip:
+		return "application/zip", nil
+	case ArchiveFormatTarGz:
+		return "application/gzip", nil
+	default:
+		return "", ErrorInvalidArchiveFormat
+	}
+}
+
+// WriteArchive stream content of files to w as archive, each file is read from storage one by one
+// without buffering whole file (except encrypted at rest file). Name of files must be in format `username/filename`
+func WriteArchive(ctx context.Context, w io.Writer, format string, filesData []*models.DataFile) error {
+	switch format {
+	case "", ArchiveFormatZip:
+		return writeZip(ctx, w, filesData)
+	case ArchiveFormatTarGz:
+		return writeTarGz(ctx, w, filesData)
+	default:
+		return ErrorInvalidArchiveFormat
+	}
+}
+
+func writeZip(ctx context.Context, w io.Writer, filesData []*models.DataFile) error {
+	zipWriter := zip.NewWriter(w)
+
+	for _, fileData := range filesData {
+		err := copyObject(ctx, fileData.Name, func(size int64) (io.Writer, error) {
+			header := &zip.FileHeader{
+				Name:     archiveEntryName(fileData.Name),
+				Method:   zip.Deflate,
+				Modified: archiveModTime(fileData),
+			}
+			header.UncompressedSize64 = uint64(size)
+
+			return zipWriter.CreateHeader(header)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+func writeTarGz(ctx context.Context, w io.Writer, filesData []*models.DataFile) error {
+	var (
+		gzipWriter = gzip.NewWriter(w)
+		tarWriter  = tar.NewWriter(gzipWriter)
+	)
+
+	for _, fileData := range filesData {
+		err := copyObject(ctx, fileData.Name, func(size int64) (io.Writer, error) {
+			// tar header need exact size, so it's taken from the opened object instead of file data
+			return tarWriter, tarWriter.WriteHeader(&tar.Header{
+				Name:    archiveEntryName(fileData.Name),
+				Mode:    0o644,
+				Size:    size,
+				ModTime: archiveModTime(fileData),
+				Format:  tar.FormatPAX,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// copyObject open the object, then copy its content to writer created by entry
+func copyObject(ctx context.Context, filePath string, entry func(size int64) (io.Writer, error)) error {
+	reader, size, err := OpenObject(ctx, filePath)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	defer func() {
+		utils.LogErr(reader.Close())
+	}()
+
+	w, err := entry(size)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func archiveEntryName(filePath string) string {
+	if split := strings.SplitN(filePath, "/", 2); len(split) > 1 {
+		return split[1]
+	}
+
+	return filePath
+}
+
+func archiveModTime(fileData *models.DataFile) time.Time {
+	if fileData.UpdatedAt > 0 {
+		return time.UnixMilli(fileData.UpdatedAt)
+	}
+
+	return time.UnixMilli(fileData.UploadedAt)
+}
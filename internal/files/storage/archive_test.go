@@ -0,0 +1,117 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveContentType(test *testing.T) {
+	contentType, err := ArchiveContentType("")
+	require.NoError(test, err)
+	assert.Equal(test, "application/zip", contentType)
+
+	contentType, err = ArchiveContentType(ArchiveFormatTarGz)
+	require.NoError(test, err)
+	assert.Equal(test, "application/gzip", contentType)
+
+	_, err = ArchiveContentType("rar")
+	assert.ErrorIs(test, err, ErrorInvalidArchiveFormat)
+}
+
+func TestArchiveEntryName(test *testing.T) {
+	assert.Equal(test, "ok.txt", archiveEntryName("test/ok.txt"))
+	assert.Equal(test, "ok.txt", archiveEntryName("ok.txt"))
+}
+
+func TestWriteArchive(test *testing.T) {
+	var (
+		username  = "testwritearchive"
+		filesData = make([]*models.DataFile, 0)
+		contents  = make(map[string][]byte)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		for _, fileData := range filesData {
+			utils.LogErr(DeleteObject(storeCtx, fileData.Name))
+		}
+	})
+
+	for i := 1; i <= 2; i++ {
+		fileData := &models.DataFile{
+			Name:         fmt.Sprintf("%s/example-%d.txt", username, i),
+			MimeType:     fiber.MIMETextPlainCharsetUTF8,
+			AutoDeleteAt: time.Now().Add(1 * time.Minute).UnixMilli(),
+			UploadedAt:   time.Now().UnixMilli(),
+		}
+		contents[archiveEntryName(fileData.Name)] = []byte(fmt.Sprintf("%s %d", test.Name(), i))
+
+		require.NoError(test, UploadObject(storeCtx, fileData.Name, contents[archiveEntryName(fileData.Name)], fileData))
+		filesData = append(filesData, fileData)
+	}
+
+	test.Run("TestOkZip", func(test *testing.T) {
+		buf := new(bytes.Buffer)
+		require.NoError(test, WriteArchive(storeCtx, buf, ArchiveFormatZip, filesData))
+
+		zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(test, err)
+		require.Len(test, zipReader.File, len(filesData))
+
+		for _, file := range zipReader.File {
+			reader, err := file.Open()
+			require.NoError(test, err)
+
+			content, err := io.ReadAll(reader)
+			require.NoError(test, err)
+			require.NoError(test, reader.Close())
+
+			assert.Equal(test, contents[file.Name], content)
+		}
+	})
+
+	test.Run("TestOkTarGz", func(test *testing.T) {
+		buf := new(bytes.Buffer)
+		require.NoError(test, WriteArchive(storeCtx, buf, ArchiveFormatTarGz, filesData))
+
+		gzipReader, err := gzip.NewReader(buf)
+		require.NoError(test, err)
+
+		var (
+			tarReader = tar.NewReader(gzipReader)
+			total     = 0
+		)
+
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(test, err)
+
+			content, err := io.ReadAll(tarReader)
+			require.NoError(test, err)
+
+			assert.Equal(test, contents[header.Name], content)
+			total++
+		}
+
+		assert.Equal(test, len(filesData), total)
+	})
+}
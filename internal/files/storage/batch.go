@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"golang.org/x/sync/errgroup"
+)
+
+const DefaultMaxConcurrency = 8
+
+// MaxConcurrency return env `STORAGE_MAX_CONCURRENCY`, maximum storage operations that run at the same time in batch
+func MaxConcurrency() int {
+	limit, err := strconv.Atoi(os.Getenv("STORAGE_MAX_CONCURRENCY"))
+	if err != nil || limit < 1 {
+		return DefaultMaxConcurrency
+	}
+
+	return limit
+}
+
+// BatchError aggregated errors of batch operation, errors is in the same order as items, nil when the item is succeed
+type BatchError struct {
+	Errors []error
+}
+
+func (batchErr *BatchError) Error() string {
+	failed := 0
+	for _, err := range batchErr.Errors {
+		if err != nil {
+			failed++
+		}
+	}
+
+	return fmt.Sprintf("%d of %d batch items failed: %s", failed, len(batchErr.Errors), errors.Join(batchErr.Errors...))
+}
+
+func (batchErr *BatchError) Unwrap() []error {
+	return batchErr.Errors
+}
+
+// Batch run fn for each item concurrently with at most MaxConcurrency at the same time,
+// item that is not started when ctx is done failed with ctx error.
+// It return *BatchError when any item is failed
+func Batch[T any](ctx context.Context, items []T, fn func(ctx context.Context, i int, item T) error) error {
+	var (
+		errs = make([]error, len(items))
+		eg   = new(errgroup.Group)
+	)
+	eg.SetLimit(MaxConcurrency())
+
+	for i, item := range items {
+		i, item := i, item
+
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		eg.Go(func() error {
+			// ctx may be done while waiting for the limit
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return nil
+			}
+
+			errs[i] = fn(ctx, i, item)
+			return nil
+		})
+	}
+
+	// every item error is kept in errs
+	_ = eg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return &BatchError{Errors: errs}
+		}
+	}
+
+	return nil
+}
+
+// BatchErrors return error of each item, or nil when err is not *BatchError
+func BatchErrors(err error) []error {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Errors
+	}
+
+	return nil
+}
+
+// GetObjects get data of each object concurrently, object that is not exist is skipped
+func GetObjects(ctx context.Context, filePaths []string) ([]*models.DataFile, error) {
+	dataFiles := make([]*models.DataFile, len(filePaths))
+
+	err := Batch(ctx, filePaths, func(ctx context.Context, i int, filePath string) error {
+		dataFile, err := GetObject(ctx, filePath)
+		if err != nil {
+			// deleted after it's listed
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		dataFiles[i] = dataFile
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*models.DataFile, 0, len(dataFiles))
+	for _, dataFile := range dataFiles {
+		if dataFile != nil {
+			found = append(found, dataFile)
+		}
+	}
+
+	return found, nil
+}
+
+// DeleteObjects delete objects concurrently
+func DeleteObjects(ctx context.Context, filePaths []string) error {
+	return Batch(ctx, filePaths, func(ctx context.Context, _ int, filePath string) error {
+		return DeleteObject(ctx, filePath)
+	})
+}
+
+// TrashObjects move objects to trash concurrently
+func TrashObjects(ctx context.Context, filePaths []string) error {
+	return Batch(ctx, filePaths, func(ctx context.Context, _ int, filePath string) error {
+		return TrashObject(ctx, filePath)
+	})
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxConcurrency(test *testing.T) {
+	test.Setenv("STORAGE_MAX_CONCURRENCY", "")
+	assert.Equal(test, DefaultMaxConcurrency, MaxConcurrency())
+
+	test.Setenv("STORAGE_MAX_CONCURRENCY", "0")
+	assert.Equal(test, DefaultMaxConcurrency, MaxConcurrency())
+
+	test.Setenv("STORAGE_MAX_CONCURRENCY", "32")
+	assert.Equal(test, 32, MaxConcurrency())
+}
+
+func TestBatch(test *testing.T) {
+	test.Setenv("STORAGE_MAX_CONCURRENCY", "2")
+
+	test.Run("TestOk", func(test *testing.T) {
+		var (
+			running = new(atomic.Int32)
+			peak    = new(atomic.Int32)
+			results = make([]int, 10)
+		)
+
+		err := Batch(context.Background(), make([]struct{}, len(results)), func(ctx context.Context, i int, _ struct{}) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				highest := peak.Load()
+				if current <= highest || peak.CompareAndSwap(highest, current) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			results[i] = i
+			return nil
+		})
+		require.NoError(test, err)
+
+		assert.LessOrEqual(test, peak.Load(), int32(2))
+		for i, result := range results {
+			assert.Equal(test, i, result)
+		}
+	})
+
+	test.Run("TestOnItemError", func(test *testing.T) {
+		errOdd := errors.New("odd_item")
+
+		err := Batch(context.Background(), []int{0, 1, 2, 3}, func(ctx context.Context, _ int, item int) error {
+			if item%2 == 1 {
+				return errOdd
+			}
+			return nil
+		})
+		require.Error(test, err)
+
+		assert.ErrorIs(test, err, errOdd)
+		assert.Equal(test, []error{nil, errOdd, nil, errOdd}, BatchErrors(err))
+	})
+
+	test.Run("TestOnCanceled", func(test *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		called := new(atomic.Int32)
+		err := Batch(ctx, []int{0, 1, 2}, func(ctx context.Context, _ int, _ int) error {
+			called.Add(1)
+			return nil
+		})
+		require.Error(test, err)
+
+		assert.ErrorIs(test, err, context.Canceled)
+		assert.Zero(test, called.Load())
+		assert.Len(test, BatchErrors(err), 3)
+	})
+
+	test.Run("TestOkEmpty", func(test *testing.T) {
+		assert.NoError(test, Batch(context.Background(), []string{}, func(ctx context.Context, _ int, _ string) error {
+			return errors.New("never_called")
+		}))
+		assert.Nil(test, BatchErrors(nil))
+	})
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// File object only reference the content, the content is stored once in blob `.tempsy/blobs/<sha256>-<md5>`,
+// and shared between all users that have the same content (e.g. the same artifact uploaded to many accounts).
+// Object without MetadataBlob is uploaded before deduplication, it store the content by itself.
+//
+// Blob cannot be encrypted with the key of one user, since it's read by every user that reference it,
+// so with encryption at rest its data key is wrapped with key derived for the blob itself (see blobScope),
+// and the key of the user is only used for object that store the content by itself.
+// Upload response is the same whether the blob already exists or not, so it never reveal that other user have the same content.
+//
+// Content of presigned upload is never read by the server, its sha256 is declared by the client, and md5 is computed by the storage.
+// Md5 is part of the key, so declaring wrong sha256 only create a blob that is never matched by other content
+const (
+	MetadataBlob       = "file-blob"      // key of blob, sha256 and md5 of plain content in hex
+	MetadataBlobSize   = "file-blob-size" // size of plain content, since reference object is empty
+	metadataBlobRefs   = "blob-refs"      // total reference to the blob
+	blobPrefix         = "blobs/"
+	maxBlobRefAttempts = 10
+)
+
+var ErrorTooManyBlobRefChanges = errors.New("too_many_concurrent_blob_reference_changes")
+
+// createBlobFunc create blob object with metadata, the object handle is only created when not exists
+type createBlobFunc func(obj *storage.ObjectHandle, metadata map[string]string) error
+
+func blobHash(fileByte []byte) string {
+	sum := sha256.Sum256(fileByte)
+	return hex.EncodeToString(sum[:])
+}
+
+// blobKey return key of blob from sha256 in hex and md5 of plain content
+func blobKey(sha256Hex string, md5Sum []byte) string {
+	return sha256Hex + "-" + hex.EncodeToString(md5Sum)
+}
+
+// contentBlobKey return key of blob for the content that is read by the server
+func contentBlobKey(fileByte []byte) string {
+	sum := md5.Sum(fileByte)
+	return blobKey(blobHash(fileByte), sum[:])
+}
+
+// blobSha256 return sha256 of the content from blob key
+func blobSha256(key string) string {
+	sha256Hex, _, _ := strings.Cut(key, "-")
+	return sha256Hex
+}
+
+func blobObject(key string) string {
+	return RecordPrefix + blobPrefix + key
+}
+
+// blobScope return encryption scope of blob object `blobs/<key>`, so each content has its own key
+func blobScope(objectName string) string {
+	return strings.TrimPrefix(objectName, RecordPrefix)
+}
+
+func blobRefs(metadata map[string]string) (int64, error) {
+	refs, err := strconv.ParseInt(metadata[metadataBlobRefs], 10, 64)
+	if err != nil {
+		return 0, errors.New("blob_refs_must_be_valid_integer")
+	}
+
+	return refs, nil
+}
+
+// writeBlob create blob from file content, encrypted with the scope of blob when encryption at rest is enabled
+func writeBlob(ctx context.Context, fileByte []byte) createBlobFunc {
+	return func(obj *storage.ObjectHandle, metadata map[string]string) error {
+		writer := obj.NewWriter(ctx)
+		writer.ContentType = fiber.MIMEOctetStream
+		writer.Metadata = metadata
+
+		// storage reject the content when it's corrupted while uploaded
+		writer.CRC32C = contentCrc32c(fileByte)
+		writer.SendCRC32C = true
+
+		if EncryptionEnabled() {
+			cipherText, encryptionMetadata, err := EncryptObject(blobScope(obj.ObjectName()), fileByte)
+			if err != nil {
+				return err
+			}
+
+			for key, value := range encryptionMetadata {
+				writer.Metadata[key] = value
+			}
+			fileByte = cipherText
+			writer.CRC32C = contentCrc32c(fileByte)
+		}
+
+		if _, err := writer.Write(fileByte); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	}
+}
+
+// copyBlob create blob from uploaded object inside the storage, only when encryption at rest is disabled
+func copyBlob(ctx context.Context, src *storage.ObjectHandle) createBlobFunc {
+	return func(obj *storage.ObjectHandle, metadata map[string]string) error {
+		copier := obj.CopierFrom(src)
+		copier.ContentType = fiber.MIMEOctetStream
+		copier.Metadata = metadata
+
+		_, err := copier.Run(ctx)
+		return err
+	}
+}
+
+// acquireBlob add reference to the blob, blob is created by create when not exists yet
+func acquireBlob(ctx context.Context, bucket *storage.BucketHandle, key string, create createBlobFunc) error {
+	obj := bucket.Object(blobObject(key))
+
+	// retry when other upload or delete change the reference at the same time
+	for attempt := 0; attempt < maxBlobRefAttempts; attempt++ {
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			if !errors.Is(err, storage.ErrObjectNotExist) {
+				return err
+			}
+
+			err = create(obj.If(storage.Conditions{DoesNotExist: true}), map[string]string{metadataBlobRefs: "1"})
+			if err == nil || !IsPreconditionFailed(err) {
+				return err
+			}
+			continue
+		}
+
+		refs, err := blobRefs(attrs.Metadata)
+		if err != nil {
+			return err
+		}
+		attrs.Metadata[metadataBlobRefs] = fmt.Sprintf("%d", refs+1)
+
+		_, err = obj.If(storage.Conditions{MetagenerationMatch: attrs.Metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{
+			Metadata: attrs.Metadata,
+		})
+		if err == nil {
+			return nil
+		}
+
+		// blob can be deleted by the last reference before updated
+		if !IsPreconditionFailed(err) && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+	}
+
+	return ErrorTooManyBlobRefChanges
+}
+
+// releaseBlob remove reference to the blob, blob is deleted when it's the last reference
+func releaseBlob(ctx context.Context, bucket *storage.BucketHandle, key string) error {
+	obj := bucket.Object(blobObject(key))
+
+	for attempt := 0; attempt < maxBlobRefAttempts; attempt++ {
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		refs, err := blobRefs(attrs.Metadata)
+		if err != nil {
+			return err
+		}
+
+		conditions := storage.Conditions{GenerationMatch: attrs.Generation, MetagenerationMatch: attrs.Metageneration}
+		if refs <= 1 {
+			err = obj.If(conditions).Delete(ctx)
+		} else {
+			attrs.Metadata[metadataBlobRefs] = fmt.Sprintf("%d", refs-1)
+
+			_, err = obj.If(conditions).Update(ctx, storage.ObjectAttrsToUpdate{
+				Metadata: attrs.Metadata,
+			})
+		}
+
+		if err == nil || errors.Is(err, storage.ErrObjectNotExist) {
+			return nil
+		}
+
+		if !IsPreconditionFailed(err) {
+			return err
+		}
+	}
+
+	return ErrorTooManyBlobRefChanges
+}
+
+// putReference write empty file object that reference the blob, the blob reference is released when it's failed
+func putReference(ctx context.Context, bucket *storage.BucketHandle, obj *storage.ObjectHandle, key string, size int64, crc32c uint32, fileData *models.DataFile) error {
+	metadata, err := objectMetadata(fileData)
+	if err != nil {
+		return errors.Join(err, releaseBlob(ctx, bucket, key))
+	}
+	metadata[MetadataBlob] = key
+	metadata[MetadataBlobSize] = fmt.Sprintf("%d", size)
+	metadata[MetadataCrc32c] = encodeCrc32c(crc32c)
+
+	writer := obj.NewWriter(ctx)
+	writer.ContentType = fileData.MimeType
+	writer.Metadata = metadata
+
+	if err = writer.Close(); err != nil {
+		return errors.Join(err, releaseBlob(ctx, bucket, key))
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"crypto/md5"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobHash(test *testing.T) {
+	assert.Equal(test, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", blobHash([]byte{}))
+	assert.Equal(test, blobHash([]byte("is ok")), blobHash([]byte("is ok")))
+	assert.NotEqual(test, blobHash([]byte("is ok")), blobHash([]byte("is not ok")))
+	assert.Equal(test, ".tempsy/blobs/abc", blobObject("abc"))
+}
+
+func TestBlobKey(test *testing.T) {
+	var (
+		fileByte = []byte("is ok")
+		md5Sum   = md5.Sum(fileByte)
+		key      = contentBlobKey(fileByte)
+	)
+
+	assert.Equal(test, blobKey(blobHash(fileByte), md5Sum[:]), key)
+	assert.Equal(test, blobHash(fileByte), blobSha256(key))
+	assert.Equal(test, "blobs/"+key, blobScope(blobObject(key)))
+
+	// the same declared sha256 of other content is other blob
+	otherMd5Sum := md5.Sum([]byte("is not ok"))
+	assert.NotEqual(test, key, blobKey(blobHash(fileByte), otherMd5Sum[:]))
+}
+
+func TestBlobRefs(test *testing.T) {
+	refs, err := blobRefs(map[string]string{metadataBlobRefs: "2"})
+	require.NoError(test, err)
+	assert.Equal(test, int64(2), refs)
+
+	_, err = blobRefs(map[string]string{})
+	require.Error(test, err)
+}
+
+func TestBlobReference(test *testing.T) {
+	var (
+		filePaths = []string{"testblobreference/ok.txt", "testblobreferenceother/ok.txt"}
+		objByte   = []byte(test.Name())
+		key       = contentBlobKey(objByte)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	client, err := createClient(storeCtx)
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		for _, filePath := range filePaths {
+			utils.LogErr(DeleteObject(storeCtx, filePath))
+		}
+		utils.LogErr(client.Close())
+	})
+
+	blob := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(blobObject(key))
+	fileData := func() *models.DataFile {
+		return &models.DataFile{
+			AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+			PrivateUrlExpires: 10,
+			MimeType:          fiber.MIMETextPlainCharsetUTF8,
+		}
+	}
+
+	for _, filePath := range filePaths {
+		require.NoError(test, UploadObject(storeCtx, filePath, objByte, fileData()))
+	}
+
+	attrs, err := blob.Attrs(storeCtx)
+	require.NoError(test, err)
+	assert.Equal(test, "2", attrs.Metadata[metadataBlobRefs])
+
+	dataFile, err := GetObject(storeCtx, filePaths[0])
+	require.NoError(test, err)
+	assert.Equal(test, int64(len(objByte)), dataFile.Size)
+	assert.Equal(test, blobSha256(key), dataFile.Sha256)
+	assert.Equal(test, encodeCrc32c(contentCrc32c(objByte)), dataFile.Crc32c)
+
+	fileByte, err := ReadObject(storeCtx, filePaths[0])
+	require.NoError(test, err)
+	assert.Equal(test, objByte, fileByte)
+
+	// the same content is not stored again
+	require.NoError(test, ReplaceObject(storeCtx, filePaths[0], objByte, fileData()))
+
+	attrs, err = blob.Attrs(storeCtx)
+	require.NoError(test, err)
+	assert.Equal(test, "2", attrs.Metadata[metadataBlobRefs])
+
+	require.NoError(test, DeleteObject(storeCtx, filePaths[0]))
+
+	attrs, err = blob.Attrs(storeCtx)
+	require.NoError(test, err)
+	assert.Equal(test, "1", attrs.Metadata[metadataBlobRefs])
+
+	require.NoError(test, DeleteObject(storeCtx, filePaths[1]))
+
+	_, err = blob.Attrs(storeCtx)
+	require.ErrorIs(test, err, storage.ErrObjectNotExist)
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Checksum headers of request, every provided checksum must match
+const (
+	HeaderContentMd5     = "content-md5"       // RFC 1864, base64 md5
+	HeaderDigest         = "digest"            // RFC 3230, e.g. `sha-256=<base64>,md5=<base64>`
+	HeaderChecksumSha256 = "x-checksum-sha256" // hex or base64 sha256
+	HeaderReprDigest     = "repr-digest"       // RFC 9530, response only
+	MetadataCrc32c       = "file-crc32c"       // crc32c of plain content
+)
+
+var (
+	ErrorChecksumMismatch = errors.New("checksum_mismatch")
+	ErrorInvalidChecksum  = errors.New("checksum_header_must_be_valid_md5_or_sha256")
+	ErrorSha256Required   = errors.New("sha256_checksum_header_is_required")
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// VerifyChecksum verify content with checksum headers, it's ok when no checksum is provided
+func VerifyChecksum(fileHeader FileHeader, fileByte []byte) error {
+	md5Sums, sha256Sums, err := parseChecksums(fileHeader)
+	if err != nil {
+		return err
+	}
+
+	if len(md5Sums) > 0 {
+		actual := md5.Sum(fileByte)
+		for _, sum := range md5Sums {
+			if !bytes.Equal(sum, actual[:]) {
+				return ErrorChecksumMismatch
+			}
+		}
+	}
+
+	if len(sha256Sums) > 0 {
+		actual := sha256.Sum256(fileByte)
+		for _, sum := range sha256Sums {
+			if !bytes.Equal(sum, actual[:]) {
+				return ErrorChecksumMismatch
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeclaredSha256 return sha256 in hex from checksum headers, for content that is not uploaded through the server.
+// Every provided sha256 must be the same
+func DeclaredSha256(fileHeader FileHeader) (string, error) {
+	_, sha256Sums, err := parseChecksums(fileHeader)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sha256Sums) == 0 {
+		return "", ErrorSha256Required
+	}
+
+	for _, sum := range sha256Sums[1:] {
+		if !bytes.Equal(sum, sha256Sums[0]) {
+			return "", ErrorChecksumMismatch
+		}
+	}
+
+	return hex.EncodeToString(sha256Sums[0]), nil
+}
+
+// parseChecksums return md5 and sha256 of every provided checksum header
+func parseChecksums(fileHeader FileHeader) ([][]byte, [][]byte, error) {
+	var (
+		md5Sums    [][]byte
+		sha256Sums [][]byte
+	)
+
+	if contentMd5 := fileHeader.Get(HeaderContentMd5); contentMd5 != "" {
+		sum, err := base64.StdEncoding.DecodeString(contentMd5)
+		if err != nil || len(sum) != md5.Size {
+			return nil, nil, ErrorInvalidChecksum
+		}
+		md5Sums = append(md5Sums, sum)
+	}
+
+	if digest := fileHeader.Get(HeaderDigest); digest != "" {
+		supported := false
+
+		for _, instance := range strings.Split(digest, ",") {
+			algorithm, value, found := strings.Cut(strings.TrimSpace(instance), "=")
+			if !found {
+				return nil, nil, ErrorInvalidChecksum
+			}
+
+			// other algorithm is ignored, as allowed by RFC 3230
+			switch strings.ToLower(algorithm) {
+			case "md5":
+				sum, err := base64.StdEncoding.DecodeString(value)
+				if err != nil || len(sum) != md5.Size {
+					return nil, nil, ErrorInvalidChecksum
+				}
+				md5Sums = append(md5Sums, sum)
+				supported = true
+			case "sha-256":
+				sum, err := base64.StdEncoding.DecodeString(value)
+				if err != nil || len(sum) != sha256.Size {
+					return nil, nil, ErrorInvalidChecksum
+				}
+				sha256Sums = append(sha256Sums, sum)
+				supported = true
+			}
+		}
+
+		if !supported {
+			return nil, nil, ErrorInvalidChecksum
+		}
+	}
+
+	if checksumSha256 := fileHeader.Get(HeaderChecksumSha256); checksumSha256 != "" {
+		sum, err := hex.DecodeString(checksumSha256)
+		if err != nil {
+			sum, err = base64.StdEncoding.DecodeString(checksumSha256)
+		}
+		if err != nil || len(sum) != sha256.Size {
+			return nil, nil, ErrorInvalidChecksum
+		}
+		sha256Sums = append(sha256Sums, sum)
+	}
+
+	return md5Sums, sha256Sums, nil
+}
+
+func contentCrc32c(fileByte []byte) uint32 {
+	return crc32.Checksum(fileByte, crc32cTable)
+}
+
+// encodeCrc32c encode crc32c as base64 of big-endian bytes, same as storage
+func encodeCrc32c(crc uint32) string {
+	crcByte := make([]byte, 4)
+	binary.BigEndian.PutUint32(crcByte, crc)
+
+	return base64.StdEncoding.EncodeToString(crcByte)
+}
+
+// SetDigestHeaders set sha256 digest of served content, it's always computed from the content
+// since stored hash can be of other generation when file is replaced while it's read
+func SetDigestHeaders(ctx *fiber.Ctx, fileByte []byte) {
+	sum := sha256.Sum256(fileByte)
+
+	digest := base64.StdEncoding.EncodeToString(sum[:])
+	ctx.Set(HeaderDigest, "sha-256="+digest)
+	ctx.Set(HeaderReprDigest, "sha-256=:"+digest+":")
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyChecksum(test *testing.T) {
+	var (
+		fileByte  = []byte("is ok")
+		md5Sum    = md5.Sum(fileByte)
+		sha256Sum = sha256.Sum256(fileByte)
+		otherSum  = sha256.Sum256([]byte("is not ok"))
+	)
+
+	tableTests := []struct {
+		name       string
+		fileHeader FileHeader
+		err        error
+	}{
+		{
+			name:       "TestOkWithoutChecksum",
+			fileHeader: FileHeader{},
+		},
+		{
+			name:       "TestOkContentMd5",
+			fileHeader: FileHeader{HeaderContentMd5: base64.StdEncoding.EncodeToString(md5Sum[:])},
+		},
+		{
+			name:       "TestOkDigest",
+			fileHeader: FileHeader{HeaderDigest: "SHA-256=" + base64.StdEncoding.EncodeToString(sha256Sum[:]) + ", unixsum=30637"},
+		},
+		{
+			name:       "TestOkChecksumSha256Hex",
+			fileHeader: FileHeader{HeaderChecksumSha256: hex.EncodeToString(sha256Sum[:])},
+		},
+		{
+			name:       "TestOkChecksumSha256Base64",
+			fileHeader: FileHeader{HeaderChecksumSha256: base64.StdEncoding.EncodeToString(sha256Sum[:])},
+		},
+		{
+			name:       "TestOnMismatchChecksumSha256",
+			fileHeader: FileHeader{HeaderChecksumSha256: hex.EncodeToString(otherSum[:])},
+			err:        ErrorChecksumMismatch,
+		},
+		{
+			name: "TestOnMismatchOneOfChecksum",
+			fileHeader: FileHeader{
+				HeaderContentMd5: base64.StdEncoding.EncodeToString(md5Sum[:]),
+				HeaderDigest:     "sha-256=" + base64.StdEncoding.EncodeToString(otherSum[:]),
+			},
+			err: ErrorChecksumMismatch,
+		},
+		{
+			name:       "TestOnInvalidContentMd5",
+			fileHeader: FileHeader{HeaderContentMd5: "invalid"},
+			err:        ErrorInvalidChecksum,
+		},
+		{
+			name:       "TestOnUnsupportedDigest",
+			fileHeader: FileHeader{HeaderDigest: "unixsum=30637"},
+			err:        ErrorInvalidChecksum,
+		},
+	}
+
+	for _, tableT := range tableTests {
+		test.Run(tableT.name, func(test *testing.T) {
+			err := VerifyChecksum(tableT.fileHeader, fileByte)
+			if tableT.err != nil {
+				require.ErrorIs(test, err, tableT.err)
+				return
+			}
+
+			require.NoError(test, err)
+		})
+	}
+}
+
+func TestDeclaredSha256(test *testing.T) {
+	var (
+		sha256Sum = sha256.Sum256([]byte("is ok"))
+		otherSum  = sha256.Sum256([]byte("is not ok"))
+	)
+
+	sha256Hex, err := DeclaredSha256(FileHeader{
+		HeaderChecksumSha256: base64.StdEncoding.EncodeToString(sha256Sum[:]),
+		HeaderDigest:         "sha-256=" + base64.StdEncoding.EncodeToString(sha256Sum[:]),
+	})
+	require.NoError(test, err)
+	assert.Equal(test, hex.EncodeToString(sha256Sum[:]), sha256Hex)
+
+	_, err = DeclaredSha256(FileHeader{HeaderContentMd5: base64.StdEncoding.EncodeToString(make([]byte, 16))})
+	require.ErrorIs(test, err, ErrorSha256Required)
+
+	_, err = DeclaredSha256(FileHeader{
+		HeaderChecksumSha256: hex.EncodeToString(sha256Sum[:]),
+		HeaderDigest:         "sha-256=" + base64.StdEncoding.EncodeToString(otherSum[:]),
+	})
+	require.ErrorIs(test, err, ErrorChecksumMismatch)
+}
+
+func TestEncodeCrc32c(test *testing.T) {
+	// check value of crc32c (Castagnoli)
+	assert.Equal(test, uint32(0xe3069283), contentCrc32c([]byte("123456789")))
+	assert.Equal(test, "4waSgw==", encodeCrc32c(0xe3069283))
+}
+
+func TestSetDigestHeaders(test *testing.T) {
+	var (
+		fileByte  = []byte("is ok")
+		sha256Sum = sha256.Sum256(fileByte)
+		digest    = base64.StdEncoding.EncodeToString(sha256Sum[:])
+	)
+
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		SetDigestHeaders(ctx, fileByte)
+		return ctx.Send(fileByte)
+	})
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), 1500*10) // 15 seconds
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		utils.LogErr(res.Body.Close())
+	})
+
+	assert.Equal(test, "sha-256="+digest, res.Header.Get(HeaderDigest))
+	assert.Equal(test, "sha-256=:"+digest+":", res.Header.Get(HeaderReprDigest))
+}
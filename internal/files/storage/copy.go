@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+)
+
+// fileMetadataKeys metadata that is managed by objectMetadata, other metadata (blob, encryption) belong to the content
+var fileMetadataKeys = []string{
+	MetadataFileId,
+	HeaderAutoDeleteAt,
+	HeaderIsPublic,
+	HeaderPrivateUrlExpires,
+	HeaderE2eHeader,
+	MetadataPasswordHash,
+	HeaderMaxVersions,
+	HeaderMaxDownloads,
+	MetadataDownloadCount,
+}
+
+// replaceFileMetadata return metadata of content from attrs with file metadata from fileData,
+// key that is not exist in file metadata has empty value, so it's removed when moved
+func replaceFileMetadata(attrs *storage.ObjectAttrs, fileData *models.DataFile) (map[string]string, error) {
+	fileMetadata, err := objectMetadata(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string, len(attrs.Metadata)+len(fileMetadata))
+	for key, value := range attrs.Metadata {
+		metadata[key] = value
+	}
+
+	for _, key := range fileMetadataKeys {
+		metadata[key] = fileMetadata[key]
+	}
+
+	return metadata, nil
+}
+
+// CopyObject copy file to dst inside the bucket without reading its content, with file metadata from fileData
+// and new file id. It's failed with precondition error when dst already exists
+func CopyObject(ctx context.Context, filePath, dst string, fileData *models.DataFile) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	attrs, err := bucket.Object(filePath).Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// copy is a new file
+	fileData.FileId = ""
+
+	metadata, err := replaceFileMetadata(attrs, fileData)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range metadata {
+		if value == "" {
+			delete(metadata, key)
+		}
+	}
+
+	// copy is another reference to the same blob
+	key := attrs.Metadata[MetadataBlob]
+	if key != "" {
+		err = acquireBlob(ctx, bucket, key, func(*storage.ObjectHandle, map[string]string) error {
+			// the file is deleted while copied
+			return storage.ErrObjectNotExist
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	copier := bucket.Object(dst).If(storage.Conditions{DoesNotExist: true}).CopierFrom(bucket.Object(filePath).Generation(attrs.Generation))
+	copier.ContentType = attrs.ContentType
+	copier.Metadata = metadata
+
+	if _, err = copier.Run(ctx); err != nil {
+		if key != "" {
+			return errors.Join(err, releaseBlob(ctx, bucket, key))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// MoveObject rename file with its versions inside the bucket without reading its content, with file metadata from fileData.
+// File id and download count is kept, since it's the same file. It's failed with precondition error when dst already exists
+func MoveObject(ctx context.Context, filePath, dst string, fileData *models.DataFile) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	attrs, err := bucket.Object(filePath).Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	fileData.FileId = attrs.Metadata[MetadataFileId]
+
+	metadata, err := replaceFileMetadata(attrs, fileData)
+	if err != nil {
+		return err
+	}
+
+	// downloaded file is not reset by rename, so burn after read cannot be bypassed
+	if fileData.MaxDownloads > 0 && attrs.Metadata[MetadataDownloadCount] != "" {
+		metadata[MetadataDownloadCount] = attrs.Metadata[MetadataDownloadCount]
+	}
+
+	if err = moveObject(ctx, bucket, attrs, dst, metadata); err != nil {
+		return err
+	}
+
+	return moveVersions(ctx, bucket, filePath, dst)
+}
+
+// TransferObject move file with its versions to other user, file metadata is kept.
+// Data key of encrypted object that is not a blob reference is wrapped again with scope of the new owner.
+// It's failed with precondition error when dst already exists
+func TransferObject(ctx context.Context, filePath, dst string) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	attrs, err := bucket.Object(filePath).Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var metadata map[string]string
+	if attrs.Metadata[MetadataBlob] == "" && IsEncrypted(attrs.Metadata) {
+		if metadata, err = RewrapDataKey(attrs.Metadata, scopeOf(dst)); err != nil {
+			return err
+		}
+	}
+
+	if err = moveObject(ctx, bucket, attrs, dst, metadata); err != nil {
+		return err
+	}
+
+	return moveVersions(ctx, bucket, filePath, dst)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceFileMetadata(test *testing.T) {
+	attrs := &storage.ObjectAttrs{
+		Metadata: map[string]string{
+			MetadataFileId:       "old",
+			MetadataPasswordHash: "hash",
+			MetadataBlob:         blobHash([]byte("is ok")),
+			MetadataBlobSize:     "5",
+		},
+	}
+
+	metadata, err := replaceFileMetadata(attrs, &models.DataFile{
+		FileId:       "new",
+		AutoDeleteAt: 1000,
+		IsPublic:     true,
+	})
+	require.NoError(test, err)
+
+	assert.Equal(test, "new", metadata[MetadataFileId])
+	assert.Equal(test, "true", metadata[HeaderIsPublic])
+	// content metadata is kept, removed file metadata is empty
+	assert.Equal(test, "5", metadata[MetadataBlobSize])
+	assert.Empty(test, metadata[MetadataPasswordHash])
+	assert.Contains(test, metadata, MetadataPasswordHash)
+}
+
+func TestCopyObject(test *testing.T) {
+	const (
+		filePath  = "testcopyobject/ok.txt"
+		copyPath  = "testcopyobject/copy.txt"
+		movePath  = "testcopyobject/moved.txt"
+		ownerPath = "testtransferobject/moved.txt"
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		for _, name := range []string{filePath, copyPath, movePath, ownerPath} {
+			utils.LogErr(DeleteObject(storeCtx, name))
+		}
+	})
+
+	fileData := func(isPublic bool) *models.DataFile {
+		return &models.DataFile{
+			AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+			PrivateUrlExpires: 10,
+			MimeType:          fiber.MIMETextPlainCharsetUTF8,
+			IsPublic:          isPublic,
+		}
+	}
+
+	require.NoError(test, UploadObject(storeCtx, filePath, []byte(test.Name()), fileData(false)))
+
+	source, err := GetObject(storeCtx, filePath)
+	require.NoError(test, err)
+
+	require.NoError(test, CopyObject(storeCtx, filePath, copyPath, fileData(true)))
+	require.True(test, IsPreconditionFailed(CopyObject(storeCtx, filePath, copyPath, fileData(true))))
+
+	copied, err := GetObject(storeCtx, copyPath)
+	require.NoError(test, err)
+	assert.True(test, copied.IsPublic)
+	assert.NotEqual(test, source.FileId, copied.FileId)
+	assert.Equal(test, source.Sha256, copied.Sha256)
+
+	// blob is still referenced by the copy
+	require.NoError(test, MoveObject(storeCtx, filePath, movePath, fileData(false)))
+
+	_, err = GetObject(storeCtx, filePath)
+	require.ErrorIs(test, err, storage.ErrObjectNotExist)
+
+	moved, err := GetObject(storeCtx, movePath)
+	require.NoError(test, err)
+	assert.Equal(test, source.FileId, moved.FileId)
+
+	fileByte, err := ReadObject(storeCtx, copyPath)
+	require.NoError(test, err)
+	assert.Equal(test, []byte(test.Name()), fileByte)
+
+	require.NoError(test, TransferObject(storeCtx, movePath, ownerPath))
+
+	transferred, err := GetObject(storeCtx, ownerPath)
+	require.NoError(test, err)
+	assert.Equal(test, source.FileId, transferred.FileId)
+	assert.Equal(test, source.Sha256, transferred.Sha256)
+
+	_, err = GetObject(storeCtx, movePath)
+	require.ErrorIs(test, err, storage.ErrObjectNotExist)
+}
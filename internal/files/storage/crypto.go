@@ -0,0 +1,230 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Metadata for envelope encryption, it's not exposed as file header
+const (
+	MetadataEncryptionKey   = "file-encryption-key"    // data key wrapped by user key
+	MetadataEncryptionKeyId = "file-encryption-key-id" // id of master key used to derive user key
+	MetadataEncryptionScope = "file-encryption-scope"  // username used to derive user key
+	encryptionOverhead      = 12 + 16                  // nonce + tag size of AES-GCM
+)
+
+var (
+	ErrorEncryptionKeyNotFound = errors.New("encryption_master_key_not_found")
+	ErrorInvalidCipherText     = errors.New("invalid_cipher_text")
+)
+
+type masterKey struct {
+	id  string
+	key []byte
+}
+
+// masterKeys parse env `STORAGE_ENCRYPTION_KEYS` in format `id:base64-key,id:base64-key`,
+// the first key is active key for encrypt, the rest only used to decrypt for key rotation
+func masterKeys() ([]*masterKey, error) {
+	keys := make([]*masterKey, 0)
+
+	for _, idKey := range envList("STORAGE_ENCRYPTION_KEYS", make([]string, 0)) {
+		split := strings.SplitN(idKey, ":", 2)
+		if len(split) != 2 {
+			return nil, errors.New("encryption_key_must_be_in_format_id_colon_base64_key")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(split[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(key) != 32 {
+			return nil, errors.New("encryption_key_must_be_32_bytes")
+		}
+
+		keys = append(keys, &masterKey{id: split[0], key: key})
+	}
+
+	return keys, nil
+}
+
+func findMasterKey(id string) (*masterKey, error) {
+	keys, err := masterKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.id == id {
+			return key, nil
+		}
+	}
+
+	return nil, ErrorEncryptionKeyNotFound
+}
+
+// EncryptionEnabled new object will be encrypted when master key is configured
+func EncryptionEnabled() bool {
+	keys, err := masterKeys()
+	return err == nil && len(keys) > 0
+}
+
+// IsEncrypted check object metadata have wrapped data key
+func IsEncrypted(metadata map[string]string) bool {
+	return metadata[MetadataEncryptionKey] != ""
+}
+
+// userKey derive per user key from master key, so each user data key is wrapped with different key
+func (mk *masterKey) userKey(scope string) []byte {
+	mac := hmac.New(sha256.New, mk.key)
+	mac.Write([]byte("tempsy-user-key:" + scope))
+
+	return mac.Sum(nil)
+}
+
+// EncryptObject encrypt plain file with new data key, and return metadata contains wrapped data key
+func EncryptObject(scope string, plain []byte) ([]byte, map[string]string, error) {
+	keys, err := masterKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil, ErrorEncryptionKeyNotFound
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err = io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	cipherText, err := seal(dataKey, plain, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata, err := wrapDataKey(keys[0], scope, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cipherText, metadata, nil
+}
+
+// DecryptObject decrypt cipher file with data key from metadata
+func DecryptObject(metadata map[string]string, cipherText []byte) ([]byte, error) {
+	dataKey, err := unwrapDataKey(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(dataKey, cipherText, nil)
+}
+
+// RewrapDataKey wrap data key with active master key and new scope,
+// file content does not need to re-encrypted since data key is not changed
+func RewrapDataKey(metadata map[string]string, scope string) (map[string]string, error) {
+	keys, err := masterKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrorEncryptionKeyNotFound
+	}
+
+	dataKey, err := unwrapDataKey(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapDataKey(keys[0], scope, dataKey)
+}
+
+// NeedRewrap check data key is not wrapped by active master key or scope is changed
+func NeedRewrap(metadata map[string]string, scope string) bool {
+	keys, err := masterKeys()
+	if err != nil || len(keys) == 0 {
+		return false
+	}
+
+	return metadata[MetadataEncryptionKeyId] != keys[0].id || metadata[MetadataEncryptionScope] != scope
+}
+
+func wrapDataKey(mk *masterKey, scope string, dataKey []byte) (map[string]string, error) {
+	wrapped, err := seal(mk.userKey(scope), dataKey, []byte(scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		MetadataEncryptionKey:   base64.StdEncoding.EncodeToString(wrapped),
+		MetadataEncryptionKeyId: mk.id,
+		MetadataEncryptionScope: scope,
+	}, nil
+}
+
+func unwrapDataKey(metadata map[string]string) ([]byte, error) {
+	mk, err := findMasterKey(metadata[MetadataEncryptionKeyId])
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[MetadataEncryptionKey])
+	if err != nil {
+		return nil, err
+	}
+
+	scope := metadata[MetadataEncryptionScope]
+
+	return open(mk.userKey(scope), wrapped, []byte(scope))
+}
+
+// seal with AES-GCM, nonce is prepended to cipher text
+func seal(key, plain, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plain)+gcm.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, additionalData), nil
+}
+
+func open(key, cipherText, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cipherText) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrorInvalidCipherText
+	}
+
+	return gcm.Open(nil, cipherText[:gcm.NonceSize()], cipherText[gcm.NonceSize():], additionalData)
+}
+
+// scopeOf return username of file path `username/filename`
+func scopeOf(filePath string) string {
+	return strings.SplitN(filePath, "/", 2)[0]
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMasterKey(test *testing.T, id string) string {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(test, err)
+
+	return id + ":" + base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEncryptObject(test *testing.T) {
+	var (
+		plain  = []byte(test.Name())
+		oldKey = newMasterKey(test, "old")
+		newKey = newMasterKey(test, "new")
+	)
+
+	test.Run("TestDisabled", func(test *testing.T) {
+		test.Setenv("STORAGE_ENCRYPTION_KEYS", "")
+
+		assert.False(test, EncryptionEnabled())
+		_, _, err := EncryptObject("test", plain)
+		require.ErrorIs(test, err, ErrorEncryptionKeyNotFound)
+	})
+
+	test.Run("TestInvalidKey", func(test *testing.T) {
+		test.Setenv("STORAGE_ENCRYPTION_KEYS", "invalid:"+base64.StdEncoding.EncodeToString([]byte("short")))
+
+		assert.False(test, EncryptionEnabled())
+	})
+
+	test.Setenv("STORAGE_ENCRYPTION_KEYS", oldKey)
+	cipherText, metadata, err := EncryptObject("test", plain)
+	require.NoError(test, err)
+
+	test.Run("TestOk", func(test *testing.T) {
+		assert.True(test, EncryptionEnabled())
+		assert.True(test, IsEncrypted(metadata))
+		assert.Len(test, cipherText, len(plain)+encryptionOverhead)
+		assert.NotContains(test, string(cipherText), string(plain))
+		assert.Equal(test, "old", metadata[MetadataEncryptionKeyId])
+		assert.Equal(test, "test", metadata[MetadataEncryptionScope])
+
+		decrypted, err := DecryptObject(metadata, cipherText)
+		require.NoError(test, err)
+		assert.Equal(test, plain, decrypted)
+	})
+
+	test.Run("TestOnOtherScope", func(test *testing.T) {
+		tampered := map[string]string{
+			MetadataEncryptionKey:   metadata[MetadataEncryptionKey],
+			MetadataEncryptionKeyId: metadata[MetadataEncryptionKeyId],
+			MetadataEncryptionScope: "other",
+		}
+
+		_, err := DecryptObject(tampered, cipherText)
+		require.Error(test, err)
+	})
+
+	test.Run("TestKeyRotation", func(test *testing.T) {
+		test.Setenv("STORAGE_ENCRYPTION_KEYS", newKey+","+oldKey)
+
+		assert.True(test, NeedRewrap(metadata, "test"))
+
+		rewrapped, err := RewrapDataKey(metadata, "test")
+		require.NoError(test, err)
+		assert.Equal(test, "new", rewrapped[MetadataEncryptionKeyId])
+		assert.False(test, NeedRewrap(rewrapped, "test"))
+
+		// old key can be removed after rewrap
+		test.Setenv("STORAGE_ENCRYPTION_KEYS", newKey)
+
+		_, err = DecryptObject(metadata, cipherText)
+		require.ErrorIs(test, err, ErrorEncryptionKeyNotFound)
+
+		decrypted, err := DecryptObject(rewrapped, cipherText)
+		require.NoError(test, err)
+		assert.Equal(test, plain, decrypted)
+	})
+
+	test.Run("TestOnInvalidCipherText", func(test *testing.T) {
+		_, err := DecryptObject(metadata, []byte("short"))
+		require.Error(test, err)
+	})
+}
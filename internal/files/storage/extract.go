@@ -0,0 +1,238 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	MaxExtractEntries = 1000
+	// MaxExtractSize total size after decompressed, extracted files is held in memory with the archive until uploaded,
+	// so it's kept at twice of maximum request body
+	MaxExtractSize = 60 << 20 // 60MB
+	// ExtractSeparator replace directory separator of entry path, since file name cannot contain path separator
+	ExtractSeparator = "_"
+)
+
+var (
+	ErrorUnsupportedArchive = errors.New("archive_must_be_zip_tar_or_gzip_compressed_tar")
+	ErrorInvalidArchive     = errors.New("archive_is_not_valid")
+	ErrorUnsafeArchiveEntry = errors.New("archive_entry_path_is_not_safe")
+	ErrorTooManyEntries     = errors.New("archive_has_too_many_entries")
+	ErrorArchiveTooLarge    = errors.New("archive_decompressed_size_is_too_large")
+	// ErrorDuplicateArchiveEntry different entry path can be flattened to the same name, e.g. `a/b_c` and `a_b/c`
+	ErrorDuplicateArchiveEntry = errors.New("archive_entries_have_the_same_name_after_directory_is_flattened")
+)
+
+// ExtractedFile regular file in archive, name is flattened entry path
+type ExtractedFile struct {
+	Name    string
+	Content []byte
+}
+
+// IsExtractable is content type of archive that can be extracted
+func IsExtractable(contentType string) bool {
+	switch MediaType(contentType) {
+	case "application/zip", "application/x-zip-compressed", "application/x-tar", "application/gzip", "application/x-gzip":
+		return true
+	default:
+		return false
+	}
+}
+
+// ExtractArchive read regular files in zip, tar or gzip compressed tar archive, directory is skipped.
+// Entry with absolute path, parent directory or link is rejected, and it's limited by MaxExtractEntries and MaxExtractSize
+// which is counted from decompressed content, not from size in entry header.
+// Archive is rejected when entries have the same flattened name, so no file is silently replaced
+func ExtractArchive(contentType string, archive []byte) ([]*ExtractedFile, error) {
+	switch MediaType(contentType) {
+	case "application/zip", "application/x-zip-compressed":
+		return extractZip(archive)
+	case "application/x-tar":
+		return extractTar(bytes.NewReader(archive))
+	case "application/gzip", "application/x-gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return nil, ErrorInvalidArchive
+		}
+
+		return extractTar(gzipReader)
+	default:
+		return nil, ErrorUnsupportedArchive
+	}
+}
+
+// extractLimit count entries and decompressed size of archive, and keep flattened names
+type extractLimit struct {
+	entries int
+	size    int64
+	names   map[string]bool
+}
+
+// name return flattened name of entry, it's failed when other entry already have the same name
+func (limit *extractLimit) name(entryPath string) (string, error) {
+	name, err := extractName(entryPath)
+	if err != nil {
+		return "", err
+	}
+
+	if limit.names == nil {
+		limit.names = make(map[string]bool)
+	}
+
+	if limit.names[name] {
+		return "", fmt.Errorf("%w: %s", ErrorDuplicateArchiveEntry, name)
+	}
+	limit.names[name] = true
+
+	return name, nil
+}
+
+// read entry content, it's failed when total size exceed MaxExtractSize
+func (limit *extractLimit) read(reader io.Reader) ([]byte, error) {
+	limit.entries++
+	if limit.entries > MaxExtractEntries {
+		return nil, ErrorTooManyEntries
+	}
+
+	content, err := io.ReadAll(io.LimitReader(reader, MaxExtractSize-limit.size+1))
+	if err != nil {
+		return nil, ErrorInvalidArchive
+	}
+
+	limit.size += int64(len(content))
+	if limit.size > MaxExtractSize {
+		return nil, ErrorArchiveTooLarge
+	}
+
+	return content, nil
+}
+
+func extractZip(archive []byte) ([]*ExtractedFile, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, ErrorInvalidArchive
+	}
+
+	var (
+		files = make([]*ExtractedFile, 0, len(zipReader.File))
+		limit = new(extractLimit)
+	)
+
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		if !file.Mode().IsRegular() {
+			return nil, ErrorUnsafeArchiveEntry
+		}
+
+		name, err := limit.name(file.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		reader, err := file.Open()
+		if err != nil {
+			return nil, ErrorInvalidArchive
+		}
+
+		content, err := limit.read(reader)
+		if closeErr := reader.Close(); err == nil && closeErr != nil {
+			// checksum of entry is verified when it's closed
+			err = ErrorInvalidArchive
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &ExtractedFile{Name: name, Content: content})
+	}
+
+	return files, nil
+}
+
+func extractTar(reader io.Reader) ([]*ExtractedFile, error) {
+	var (
+		tarReader = tar.NewReader(reader)
+		files     = make([]*ExtractedFile, 0)
+		limit     = new(extractLimit)
+	)
+
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return files, nil
+			}
+			return nil, ErrorInvalidArchive
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			continue
+		case tar.TypeReg:
+		default:
+			// link and device can point outside of extracted files
+			return nil, ErrorUnsafeArchiveEntry
+		}
+
+		name, err := limit.name(header.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := limit.read(tarReader)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &ExtractedFile{Name: name, Content: content})
+	}
+}
+
+// extractName flatten entry path to file name, e.g. `reports/coverage/index.html` become `reports_coverage_index.html`.
+// Path that is absolute or contain parent directory is rejected (zip slip)
+func extractName(entryPath string) (string, error) {
+	if entryPath == "" || strings.Contains(entryPath, "\\") || path.IsAbs(entryPath) {
+		return "", ErrorUnsafeArchiveEntry
+	}
+
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(entryPath, "/") {
+		switch segment {
+		case "..":
+			return "", ErrorUnsafeArchiveEntry
+		case "", ".":
+			continue
+		default:
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "", ErrorUnsafeArchiveEntry
+	}
+
+	return strings.Join(segments, ExtractSeparator), nil
+}
+
+// DetectContentType return content type of extracted file from its extension,
+// or from magic bytes when extension is unknown
+func DetectContentType(fileName string, fileByte []byte) string {
+	if contentType := mime.TypeByExtension(path.Ext(fileName)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(fileByte)
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarArchive(test *testing.T, headers []*tar.Header) []byte {
+	var (
+		buf       = new(bytes.Buffer)
+		tarWriter = tar.NewWriter(buf)
+	)
+
+	for _, header := range headers {
+		content := make([]byte, header.Size)
+		copy(content, header.Name)
+
+		require.NoError(test, tarWriter.WriteHeader(header))
+
+		_, err := tarWriter.Write(content)
+		require.NoError(test, err)
+	}
+	require.NoError(test, tarWriter.Close())
+
+	return buf.Bytes()
+}
+
+func TestExtractName(test *testing.T) {
+	tableTests := []struct {
+		entryPath string
+		name      string
+		err       error
+	}{
+		{entryPath: "index.html", name: "index.html"},
+		{entryPath: "reports/coverage/index.html", name: "reports_coverage_index.html"},
+		{entryPath: "./reports//index.html", name: "reports_index.html"},
+		{entryPath: "../index.html", err: ErrorUnsafeArchiveEntry},
+		{entryPath: "reports/../../index.html", err: ErrorUnsafeArchiveEntry},
+		{entryPath: "/etc/passwd.txt", err: ErrorUnsafeArchiveEntry},
+		{entryPath: "..\\index.html", err: ErrorUnsafeArchiveEntry},
+		{entryPath: "./", err: ErrorUnsafeArchiveEntry},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.entryPath, func(test *testing.T) {
+			name, err := extractName(table.entryPath)
+			if table.err != nil {
+				assert.ErrorIs(test, err, table.err)
+				return
+			}
+
+			require.NoError(test, err)
+			assert.Equal(test, table.name, name)
+		})
+	}
+}
+
+func TestExtractArchive(test *testing.T) {
+	test.Run("TestOkZip", func(test *testing.T) {
+		var (
+			buf       = new(bytes.Buffer)
+			zipWriter = zip.NewWriter(buf)
+		)
+
+		_, err := zipWriter.Create("reports/")
+		require.NoError(test, err)
+
+		w, err := zipWriter.Create("reports/index.html")
+		require.NoError(test, err)
+		_, err = w.Write([]byte("<html></html>"))
+		require.NoError(test, err)
+		require.NoError(test, zipWriter.Close())
+
+		files, err := ExtractArchive("application/zip", buf.Bytes())
+		require.NoError(test, err)
+		require.Len(test, files, 1)
+
+		assert.Equal(test, "reports_index.html", files[0].Name)
+		assert.Equal(test, []byte("<html></html>"), files[0].Content)
+	})
+
+	test.Run("TestOkTarGz", func(test *testing.T) {
+		var (
+			buf        = new(bytes.Buffer)
+			gzipWriter = gzip.NewWriter(buf)
+		)
+
+		_, err := gzipWriter.Write(tarArchive(test, []*tar.Header{
+			{Name: "reports/", Typeflag: tar.TypeDir, Mode: 0o755},
+			{Name: "reports/ok.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 14},
+		}))
+		require.NoError(test, err)
+		require.NoError(test, gzipWriter.Close())
+
+		files, err := ExtractArchive("application/gzip", buf.Bytes())
+		require.NoError(test, err)
+		require.Len(test, files, 1)
+
+		assert.Equal(test, "reports_ok.txt", files[0].Name)
+		assert.Equal(test, []byte("reports/ok.txt"), files[0].Content)
+	})
+
+	test.Run("TestOnSymlink", func(test *testing.T) {
+		_, err := ExtractArchive("application/x-tar", tarArchive(test, []*tar.Header{
+			{Name: "passwd.txt", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd", Mode: 0o777},
+		}))
+		assert.ErrorIs(test, err, ErrorUnsafeArchiveEntry)
+	})
+
+	test.Run("TestOnZipSlip", func(test *testing.T) {
+		_, err := ExtractArchive("application/x-tar", tarArchive(test, []*tar.Header{
+			{Name: "../../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		}))
+		assert.ErrorIs(test, err, ErrorUnsafeArchiveEntry)
+	})
+
+	test.Run("TestOnDuplicateName", func(test *testing.T) {
+		_, err := ExtractArchive("application/x-tar", tarArchive(test, []*tar.Header{
+			{Name: "a/b_c.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "a_b/c.txt", Typeflag: tar.TypeReg, Mode: 0o644},
+		}))
+		assert.ErrorIs(test, err, ErrorDuplicateArchiveEntry)
+		assert.ErrorContains(test, err, "a_b_c.txt")
+	})
+
+	test.Run("TestOnTooManyEntries", func(test *testing.T) {
+		headers := make([]*tar.Header, MaxExtractEntries+1)
+		for i := range headers {
+			headers[i] = &tar.Header{Name: fmt.Sprintf("%d.txt", i), Typeflag: tar.TypeReg, Mode: 0o644}
+		}
+
+		_, err := ExtractArchive("application/x-tar", tarArchive(test, headers))
+		assert.ErrorIs(test, err, ErrorTooManyEntries)
+	})
+
+	test.Run("TestOnTooLarge", func(test *testing.T) {
+		var (
+			buf        = new(bytes.Buffer)
+			gzipWriter = gzip.NewWriter(buf)
+		)
+
+		// highly compressed, but it's limited by decompressed size
+		_, err := gzipWriter.Write(tarArchive(test, []*tar.Header{
+			{Name: "bomb.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: MaxExtractSize + 1},
+		}))
+		require.NoError(test, err)
+		require.NoError(test, gzipWriter.Close())
+
+		_, err = ExtractArchive("application/gzip", buf.Bytes())
+		assert.ErrorIs(test, err, ErrorArchiveTooLarge)
+	})
+
+	test.Run("TestOnInvalidArchive", func(test *testing.T) {
+		_, err := ExtractArchive("application/zip", []byte("not zip"))
+		assert.ErrorIs(test, err, ErrorInvalidArchive)
+
+		_, err = ExtractArchive("text/plain", []byte("not zip"))
+		assert.ErrorIs(test, err, ErrorUnsupportedArchive)
+	})
+}
+
+func TestDetectContentType(test *testing.T) {
+	assert.Equal(test, "text/html; charset=utf-8", DetectContentType("index.html", []byte("<html></html>")))
+	assert.Equal(test, "text/plain; charset=utf-8", DetectContentType("notes.unknownext", []byte("notes")))
+}
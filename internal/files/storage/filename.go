@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MaxFileNameLength in bytes, most filesystem and storage backend limit name segment to 255 bytes
+	MaxFileNameLength = 255
+	// MaxConflictNumber maximum number of ConflictFileName that is tried before giving up
+	MaxConflictNumber = 100
+)
+
+// reserved device names on Windows, cannot be used as file name even with extension (e.g. `con.txt`)
+var reservedFileNames = []string{
+	"con", "prn", "aux", "nul",
+	"com1", "com2", "com3", "com4", "com5", "com6", "com7", "com8", "com9",
+	"lpt1", "lpt2", "lpt3", "lpt4", "lpt5", "lpt6", "lpt7", "lpt8", "lpt9",
+}
+
+// DecodeFileName decode percent encoded file name from path params or header and normalize it to NFC,
+// so it's match with stored file name. Raw value is used if it's not valid percent encoding (e.g. `100%.txt`)
+func DecodeFileName(raw string) string {
+	decoded, err := url.PathUnescape(raw)
+	if err != nil {
+		decoded = raw
+	}
+
+	return norm.NFC.String(decoded)
+}
+
+// ValidateFileName return NFC normalized file name, or error if file name is not allowed.
+// Unicode letters and spaces are allowed, but control characters, path separator
+// and reserved names are not, and file name must contain extension separated by dot
+func ValidateFileName(fileName string) (string, error) {
+	if !utf8.ValidString(fileName) {
+		return "", errors.New("file_name_must_be_valid_utf8")
+	}
+
+	fileName = norm.NFC.String(fileName)
+
+	if fileName == "" || len(fileName) > MaxFileNameLength {
+		return "", fmt.Errorf("file_name_must_be_between_1_and_%d_bytes", MaxFileNameLength)
+	}
+
+	for _, char := range fileName {
+		// Cf include invisible bidi override, that can be used to spoof extension
+		if unicode.IsControl(char) || unicode.Is(unicode.Cf, char) {
+			return "", errors.New("file_name_cannot_contain_control_characters")
+		}
+
+		if char == '/' || char == '\\' {
+			return "", errors.New("file_name_cannot_contain_path_separator")
+		}
+	}
+
+	if strings.HasPrefix(fileName, " ") || strings.HasSuffix(fileName, " ") || strings.HasSuffix(fileName, ".") {
+		return "", errors.New("file_name_cannot_start_or_end_with_space_or_end_with_dot")
+	}
+
+	extIndex := strings.LastIndex(fileName, ".")
+	if extIndex < 1 || extIndex == len(fileName)-1 {
+		return "", errors.New("file_name_must_contain_extension_separated_by_dot")
+	}
+
+	baseName := strings.ToLower(strings.TrimSpace(strings.SplitN(fileName, ".", 2)[0]))
+	for _, reserved := range reservedFileNames {
+		if baseName == reserved {
+			return "", errors.New("file_name_is_reserved")
+		}
+	}
+
+	return fileName, nil
+}
+
+// ConflictFileName return file name with number before the extension, to resolve name conflict,
+// e.g. `report.tar.gz` become `report (1).tar.gz`
+func ConflictFileName(fileName string, number int) string {
+	// leading dot is part of the name (e.g. `.env.local`)
+	extIndex := strings.Index(strings.TrimPrefix(fileName, "."), ".")
+	if extIndex < 0 {
+		return fmt.Sprintf("%s (%d)", fileName, number)
+	}
+	extIndex += len(fileName) - len(strings.TrimPrefix(fileName, "."))
+
+	return fmt.Sprintf("%s (%d)%s", fileName[:extIndex], number, fileName[extIndex:])
+}
+
+// ContentDisposition create Content-Disposition header value with ASCII fallback `filename`
+// and RFC 5987 `filename*` for non ASCII file name
+func ContentDisposition(dispositionType, fileName string) string {
+	var (
+		fallback = new(strings.Builder)
+		encoded  = new(strings.Builder)
+	)
+
+	for _, char := range fileName {
+		if char < utf8.RuneSelf && char >= 0x20 && char != '"' && char != '\\' && char != 0x7f {
+			fallback.WriteRune(char)
+		} else {
+			fallback.WriteRune('_')
+		}
+	}
+
+	// attr-char from RFC 5987, everything else must be percent encoded
+	for _, b := range []byte(fileName) {
+		if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(encoded, "%%%02X", b)
+		}
+	}
+
+	return fmt.Sprintf(`%s; filename="%s"; filename*=UTF-8''%s`, dispositionType, fallback.String(), encoded.String())
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFileName(test *testing.T) {
+	tableTests := []struct {
+		name     string
+		fileName string
+		expected string
+		err      string
+	}{
+		{
+			name:     "TestOkAscii",
+			fileName: "example.txt",
+			expected: "example.txt",
+		},
+		{
+			name:     "TestOkSpace",
+			fileName: "report 2024.pdf",
+			expected: "report 2024.pdf",
+		},
+		{
+			name:     "TestOkMultipleDot",
+			fileName: "archive.tar.gz",
+			expected: "archive.tar.gz",
+		},
+		{
+			name:     "TestOkNormalizeNFC",
+			fileName: "re\u0301sume\u0301.docx", // decomposed é
+			expected: "résumé.docx",
+		},
+		{
+			name:     "TestOnWithoutExtension",
+			fileName: "example",
+			err:      "file_name_must_contain_extension_separated_by_dot",
+		},
+		{
+			name:     "TestOnHiddenWithoutName",
+			fileName: ".env",
+			err:      "file_name_must_contain_extension_separated_by_dot",
+		},
+		{
+			name:     "TestOnControlCharacter",
+			fileName: "new\nline.txt",
+			err:      "file_name_cannot_contain_control_characters",
+		},
+		{
+			name:     "TestOnBidiOverride",
+			fileName: "invoice\u202efdp.exe",
+			err:      "file_name_cannot_contain_control_characters",
+		},
+		{
+			name:     "TestOnPathSeparator",
+			fileName: "../secret.txt",
+			err:      "file_name_cannot_contain_path_separator",
+		},
+		{
+			name:     "TestOnTrailingDot",
+			fileName: "example.txt.",
+			err:      "file_name_cannot_start_or_end_with_space_or_end_with_dot",
+		},
+		{
+			name:     "TestOnReservedName",
+			fileName: "CON.txt",
+			err:      "file_name_is_reserved",
+		},
+		{
+			name:     "TestOnTooLong",
+			fileName: strings.Repeat("a", MaxFileNameLength) + ".txt",
+			err:      "file_name_must_be_between_1_and_255_bytes",
+		},
+		{
+			name:     "TestOnInvalidUtf8",
+			fileName: "\xff.txt",
+			err:      "file_name_must_be_valid_utf8",
+		},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			fileName, err := ValidateFileName(tt.fileName)
+			if tt.err != "" {
+				require.Error(test, err)
+				assert.Equal(test, tt.err, err.Error())
+				assert.Empty(test, fileName)
+			} else {
+				require.NoError(test, err)
+				assert.Equal(test, tt.expected, fileName)
+			}
+		})
+	}
+}
+
+func TestDecodeFileName(test *testing.T) {
+	assert.Equal(test, "résumé 2024.docx", DecodeFileName("re%CC%81sume%CC%81%202024.docx"))
+	assert.Equal(test, "100%.txt", DecodeFileName("100%.txt"))
+}
+
+func TestFormatEscapeFileName(test *testing.T) {
+	dataFile := &models.DataFile{
+		Name:     "test/résumé 2024.docx",
+		IsPublic: true,
+	}
+
+	Format(dataFile)
+
+	assert.Equal(test, "résumé 2024.docx", dataFile.Name)
+	assert.True(test, strings.HasSuffix(dataFile.Url, "/files/test/public/r%C3%A9sum%C3%A9%202024.docx"))
+
+	// round-trip from url path to file name
+	assert.Equal(test, dataFile.Name, DecodeFileName(dataFile.Url[strings.LastIndex(dataFile.Url, "/")+1:]))
+}
+
+func TestContentDisposition(test *testing.T) {
+	assert.Equal(test, `inline; filename="example.txt"; filename*=UTF-8''example.txt`, ContentDisposition("inline", "example.txt"))
+	assert.Equal(test, `attachment; filename="r_sum_ 2024.docx"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.docx`, ContentDisposition("attachment", "résumé 2024.docx"))
+	assert.Equal(test, `inline; filename="a_b_.txt"; filename*=UTF-8''a%22b%5C.txt`, ContentDisposition("inline", `a"b\.txt`))
+}
+
+func TestConflictFileName(test *testing.T) {
+	assert.Equal(test, "report (1).txt", ConflictFileName("report.txt", 1))
+	assert.Equal(test, "report (2).tar.gz", ConflictFileName("report.tar.gz", 2))
+	assert.Equal(test, ".env (1).local", ConflictFileName(".env.local", 1))
+}
@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderGrantRole   = "grant-role"
+	GrantRoleRead     = "read"
+	GrantRoleWrite    = "write"
+	MaxUserNameLength = 128
+	// LocalGrant key of grant in fiber locals, when file is accessed by other user with grant
+	LocalGrant            = "grant"
+	metadataGrantGrantee  = "grant-grantee"
+	metadataGrantFilePath = "grant-file-path"
+	grantRecordPrefix     = "grants/"
+)
+
+var (
+	ErrorGrantNotFound    = errors.New("grant_not_found")
+	ErrorInvalidGrantRole = errors.New("grant_role_must_be_read_or_write")
+	ErrorInvalidGrantee   = errors.New("grantee_must_be_valid_username_other_than_owner")
+	ErrorInvalidUserName  = errors.New("username_must_not_contain_dot_colon_or_path_separator")
+)
+
+// GrantRecord access of other user to the file, it's stored as record `grants/<owner>/<filename>/<grantee>`
+type GrantRecord struct {
+	FilePath  string `json:"filePath"`
+	FileId    string `json:"fileId"`
+	Grantee   string `json:"grantee"`
+	Role      string `json:"role"`
+	CreatedAt int64  `json:"createdAt"` // in milliseconds
+}
+
+func (record *GrantRecord) Grant() *models.Grant {
+	split := strings.SplitN(record.FilePath, "/", 2)
+
+	return &models.Grant{
+		Owner:     split[0],
+		FileName:  split[1],
+		Grantee:   record.Grantee,
+		Role:      record.Role,
+		CreatedAt: record.CreatedAt,
+	}
+}
+
+// GrantFromCtx return grant that is loaded by middleware, nil when file is accessed by its owner or organization member
+func GrantFromCtx(ctx *fiber.Ctx) *GrantRecord {
+	record, _ := ctx.Locals(LocalGrant).(*GrantRecord)
+	return record
+}
+
+// Allows check the grant is enough for role, write access include read access
+func (record *GrantRecord) Allows(role string) bool {
+	return record.Role == GrantRoleWrite || record.Role == role
+}
+
+func ValidateGrantRole(role string) error {
+	if role != GrantRoleRead && role != GrantRoleWrite {
+		return ErrorInvalidGrantRole
+	}
+
+	return nil
+}
+
+// ValidateUserName check username of other user, username never contain dot, colon or path separator
+func ValidateUserName(username string) error {
+	if username == "" || len(username) > MaxUserNameLength || strings.ContainsAny(username, ".:/\\") {
+		return ErrorInvalidUserName
+	}
+
+	return nil
+}
+
+func ValidateGrantee(owner, grantee string) error {
+	if grantee == owner || ValidateUserName(grantee) != nil {
+		return ErrorInvalidGrantee
+	}
+
+	return nil
+}
+
+func grantRecordName(filePath, grantee string) string {
+	return grantRecordPrefix + filePath + "/" + grantee
+}
+
+// PutGrant create grant or replace role of existing grant
+func PutGrant(ctx context.Context, record *GrantRecord) error {
+	record.CreatedAt = time.Now().UnixMilli()
+
+	return WriteRecord(ctx, grantRecordName(record.FilePath, record.Grantee), record, RecordOverwrite, map[string]string{
+		metadataGrantGrantee:  record.Grantee,
+		metadataGrantFilePath: record.FilePath,
+	})
+}
+
+// GetGrant return ErrorGrantNotFound when grantee has no access to the file,
+// grant of previous file with the same name must be checked by the caller with file id
+func GetGrant(ctx context.Context, filePath, grantee string) (*GrantRecord, error) {
+	record := new(GrantRecord)
+	if _, err := ReadRecord(ctx, grantRecordName(filePath, grantee), record); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrorGrantNotFound
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ListGrants return all grant of file
+func ListGrants(ctx context.Context, filePath string) ([]*GrantRecord, error) {
+	names, err := ListRecords(ctx, grantRecordName(filePath, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	return readGrants(ctx, names)
+}
+
+// ListReceivedGrants return all grant that is given to grantee by other users
+func ListReceivedGrants(ctx context.Context, grantee string) ([]*GrantRecord, error) {
+	names, err := ListRecords(ctx, grantRecordPrefix, func(metadata map[string]string) bool {
+		return metadata[metadataGrantGrantee] == grantee
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return readGrants(ctx, names)
+}
+
+func readGrants(ctx context.Context, names []string) ([]*GrantRecord, error) {
+	records := make([]*GrantRecord, 0, len(names))
+	for _, name := range names {
+		record := new(GrantRecord)
+		if _, err := ReadRecord(ctx, name, record); err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				continue // revoked while listing
+			}
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func RevokeGrant(ctx context.Context, filePath, grantee string) error {
+	if err := DeleteRecord(ctx, grantRecordName(filePath, grantee)); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorGrantNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RevokeGrants revoke all grant of file, e.g. when the file is transferred to other user
+func RevokeGrants(ctx context.Context, filePath string) error {
+	names, err := ListRecords(ctx, grantRecordName(filePath, ""))
+	if err != nil {
+		return err
+	}
+
+	return Batch(ctx, names, func(ctx context.Context, _ int, name string) error {
+		if err := DeleteRecord(ctx, name); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGrant(test *testing.T) {
+	assert.NoError(test, ValidateGrantRole(GrantRoleRead))
+	assert.NoError(test, ValidateGrantRole(GrantRoleWrite))
+	assert.ErrorIs(test, ValidateGrantRole("owner"), ErrorInvalidGrantRole)
+
+	assert.NoError(test, ValidateGrantee("owner", "grantee-gmail-com"))
+
+	for _, grantee := range []string{"", "owner", "grantee.gmail.com", "../owner", "grantee\\owner"} {
+		assert.ErrorIs(test, ValidateGrantee("owner", grantee), ErrorInvalidGrantee, grantee)
+	}
+}
+
+func TestGrantRecord(test *testing.T) {
+	record := &GrantRecord{FilePath: "owner/ok.txt", Grantee: "grantee", Role: GrantRoleRead}
+
+	assert.True(test, record.Allows(GrantRoleRead))
+	assert.False(test, record.Allows(GrantRoleWrite))
+
+	record.Role = GrantRoleWrite
+	assert.True(test, record.Allows(GrantRoleRead))
+	assert.True(test, record.Allows(GrantRoleWrite))
+
+	grant := record.Grant()
+	assert.Equal(test, "owner", grant.Owner)
+	assert.Equal(test, "ok.txt", grant.FileName)
+}
+
+func TestPutGrant(test *testing.T) {
+	const (
+		filePath = "testputgrant/ok.txt"
+		grantee  = "testputgrantee"
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+	test.Cleanup(cancel)
+
+	require.NoError(test, PutGrant(storeCtx, &GrantRecord{FilePath: filePath, FileId: "file-id", Grantee: grantee, Role: GrantRoleRead}))
+	// replace the role
+	require.NoError(test, PutGrant(storeCtx, &GrantRecord{FilePath: filePath, FileId: "file-id", Grantee: grantee, Role: GrantRoleWrite}))
+
+	record, err := GetGrant(storeCtx, filePath, grantee)
+	require.NoError(test, err)
+	assert.Equal(test, GrantRoleWrite, record.Role)
+
+	records, err := ListGrants(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Len(test, records, 1)
+
+	records, err = ListReceivedGrants(storeCtx, grantee)
+	require.NoError(test, err)
+	assert.Len(test, records, 1)
+
+	require.NoError(test, RevokeGrant(storeCtx, filePath, grantee))
+	assert.ErrorIs(test, RevokeGrant(storeCtx, filePath, grantee), ErrorGrantNotFound)
+
+	_, err = GetGrant(storeCtx, filePath, grantee)
+	assert.ErrorIs(test, err, ErrorGrantNotFound)
+}
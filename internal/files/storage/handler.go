@@ -1,22 +1,27 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
-	"github.com/gofiber/fiber/v2"
-	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/iterator"
 )
 
+const maxConsumeDownloadAttempts = 10
+
+var ErrorDownloadLimitReached = errors.New("download_limit_reached")
+
 func ListObjects(ctx context.Context, path string, filter ...func(data *models.DataFile) bool) ([]*models.DataFile, error) {
 	client, err := createClient(ctx)
 	if err != nil {
@@ -31,52 +36,36 @@ func ListObjects(ctx context.Context, path string, filter ...func(data *models.D
 		objects = bucket.Objects(ctx, &storage.Query{Prefix: path})
 	)
 
-	var (
-		eg          = new(errgroup.Group)
-		mu          = new(sync.Mutex)
-		dataFiles   = new([]*models.DataFile)
-		objectNames = new([]string)
-	)
-
-	eg.Go(func() error {
-		defer mu.Unlock()
-		mu.Lock()
-		for {
-			obj, err := objects.Next()
-			if err != nil {
-				if errors.Is(err, iterator.Done) {
-					break
-				}
-				return err
+	objectNames := make([]string, 0)
+	for {
+		obj, err := objects.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				break
 			}
-
-			*objectNames = append(*objectNames, obj.Name)
+			return nil, err
 		}
 
-		for _, objectName := range *objectNames {
-			dataFile, err := GetObject(ctx, objectName)
-			if err != nil {
-				return err
-			}
-
-			if len(filter) > 0 && filter[0] != nil {
-				if filter[0](dataFile) {
-					*dataFiles = append(*dataFiles, dataFile)
-				}
-			} else {
-				*dataFiles = append(*dataFiles, dataFile)
-			}
+		objectNames = append(objectNames, obj.Name)
+	}
 
-		}
+	dataFiles, err := GetObjects(ctx, objectNames)
+	if err != nil {
+		return nil, err
+	}
 
-		if len(*dataFiles) == 0 {
-			*dataFiles = make([]*models.DataFile, 0)
+	if len(filter) > 0 && filter[0] != nil {
+		filtered := make([]*models.DataFile, 0, len(dataFiles))
+		for _, dataFile := range dataFiles {
+			if filter[0](dataFile) {
+				filtered = append(filtered, dataFile)
+			}
 		}
 
-		return nil
-	})
+		return filtered, nil
+	}
 
-	return *dataFiles, eg.Wait()
+	return dataFiles, nil
 }
 
 // GetObject return Name object will be in format `username/filename` as standard format in upload file
@@ -101,28 +90,35 @@ func GetObject(ctx context.Context, filePath string) (*models.DataFile, error) {
 		UpdatedAt:  attrs.Updated.UnixMilli(),
 		MimeType:   attrs.ContentType,
 		Size:       attrs.Size,
+		FileId:     attrs.Metadata[MetadataFileId],
 	}
 
 	if err = UnmarshalMetadata(attrs.Metadata, fileData); err != nil {
 		return nil, err
 	}
 
-	url, err := bucket.SignedURL(filePath, &storage.SignedURLOptions{
-		Method:   fiber.MethodGet,
-		Scheme:   storage.SigningSchemeV4,
-		Expires:  time.Now().Add(time.Duration(fileData.PrivateUrlExpires) * time.Second),
-		Insecure: os.Getenv("APP_ENV") != "production",
-	})
-	if err != nil {
-		return nil, err
+	if key := attrs.Metadata[MetadataBlob]; key != "" {
+		if fileData.Size, err = strconv.ParseInt(attrs.Metadata[MetadataBlobSize], 10, 64); err != nil {
+			return nil, errors.New("blob_size_must_be_valid_integer")
+		}
+
+		fileData.Sha256 = blobSha256(key)
+		fileData.Crc32c = attrs.Metadata[MetadataCrc32c]
+	} else if IsEncrypted(attrs.Metadata) {
+		fileData.Size -= encryptionOverhead
+	} else {
+		// checksum of storage is only valid for plain content
+		fileData.Crc32c = encodeCrc32c(attrs.CRC32C)
 	}
 
-	fileData.Url = url
+	// signed by the server instead of storage, so bucket is not exposed and file is always decrypted
+	fileData.Url = DownloadUrl(filePath, fileData.FileId, time.Now().Add(time.Duration(fileData.PrivateUrlExpires)*time.Second))
 
 	return fileData, nil
 }
 
-// UploadObject filePath must be in format `username/filename`
+// UploadObject filePath must be in format `username/filename`,
+// the content is stored once, and shared with other file of any user that have the same content
 func UploadObject(ctx context.Context, filePath string, fileByte []byte, fileData *models.DataFile) error {
 	if !strings.Contains(filePath, "/") {
 		return errors.New("invalid_file_path")
@@ -136,25 +132,410 @@ func UploadObject(ctx context.Context, filePath string, fileByte []byte, fileDat
 		utils.LogErr(client.Close())
 	}()
 
-	obj := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(filePath)
+	var (
+		bucket = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		key    = contentBlobKey(fileByte)
+	)
 
-	obj = obj.If(storage.Conditions{DoesNotExist: true})
+	if err = acquireBlob(ctx, bucket, key, writeBlob(ctx, fileByte)); err != nil {
+		return err
+	}
+
+	return putReference(ctx, bucket, bucket.Object(filePath).If(storage.Conditions{DoesNotExist: true}), key, int64(len(fileByte)), contentCrc32c(fileByte), fileData)
+}
 
-	writer := obj.NewWriter(ctx)
+// ReplaceObject replace content and metadata of existing object, the new content is referenced
+// before the old one is released, so updating with the same content never delete the blob.
+// The old content is kept as version when versioning is enabled
+func ReplaceObject(ctx context.Context, filePath string, fileByte []byte, fileData *models.DataFile) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		bucket = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		obj    = bucket.Object(filePath)
+		key    = contentBlobKey(fileByte)
+	)
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	// previous content must be kept before it's replaced
+	if fileData.MaxVersions != 0 {
+		if err = keepVersion(ctx, bucket, filePath, attrs); err != nil {
+			return err
+		}
+	}
+
+	if err = acquireBlob(ctx, bucket, key, writeBlob(ctx, fileByte)); err != nil {
+		return err
+	}
+
+	if err = putReference(ctx, bucket, obj.If(storage.Conditions{GenerationMatch: attrs.Generation}), key, int64(len(fileByte)), contentCrc32c(fileByte), fileData); err != nil {
+		return err
+	}
+
+	if oldKey := attrs.Metadata[MetadataBlob]; oldKey != "" {
+		if err = releaseBlob(ctx, bucket, oldKey); err != nil {
+			return err
+		}
+	}
+
+	// disabled versioning delete the history
+	return pruneVersions(ctx, bucket, filePath, fileData.MaxVersions)
+}
 
-	writer.Metadata = map[string]string{
+// objectMetadata map file data to object metadata
+func objectMetadata(fileData *models.DataFile) (map[string]string, error) {
+	// keep the same id when file is updated
+	if fileData.FileId == "" {
+		fileId, err := randomId(16)
+		if err != nil {
+			return nil, err
+		}
+
+		fileData.FileId = fileId
+	}
+
+	metadata := map[string]string{
+		MetadataFileId:          fileData.FileId,
 		HeaderAutoDeleteAt:      fmt.Sprintf("%d", fileData.AutoDeleteAt),
 		HeaderIsPublic:          fmt.Sprintf("%t", fileData.IsPublic),
 		HeaderPrivateUrlExpires: fmt.Sprintf("%d", fileData.PrivateUrlExpires),
 	}
 
-	writer.ContentType = fileData.MimeType
+	if fileData.E2eHeader != "" {
+		metadata[HeaderE2eHeader] = fileData.E2eHeader
+	}
 
-	if _, err = writer.Write(fileByte); err != nil {
-		return err
+	if fileData.PasswordHash != "" {
+		metadata[MetadataPasswordHash] = fileData.PasswordHash
+	}
+
+	if fileData.MaxVersions != 0 {
+		metadata[HeaderMaxVersions] = fmt.Sprintf("%d", fileData.MaxVersions)
+	}
+
+	// new content always start from zero download
+	if fileData.MaxDownloads > 0 {
+		metadata[HeaderMaxDownloads] = fmt.Sprintf("%d", fileData.MaxDownloads)
+		metadata[MetadataDownloadCount] = "0"
+	}
+
+	return metadata, nil
+}
+
+// ReadObject return content of object, decrypted if object is encrypted
+func ReadObject(ctx context.Context, filePath string) ([]byte, error) {
+	reader, _, err := OpenObject(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.LogErr(reader.Close())
+	}()
+
+	return io.ReadAll(reader)
+}
+
+// objectReader close the client after the object is read
+type objectReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (reader *objectReader) Close() error {
+	errs := make([]error, 0, len(reader.closers))
+	for _, closer := range reader.closers {
+		errs = append(errs, closer.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// OpenObject return reader of object content and its size, decrypted if object is encrypted.
+// Encrypted object is sealed as a whole, so it's read to memory first, otherwise it's streamed from storage
+func OpenObject(ctx context.Context, filePath string) (io.ReadCloser, int64, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		bucket = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		obj    = bucket.Object(filePath)
+	)
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		utils.LogErr(client.Close())
+		return nil, 0, err
+	}
+
+	// reference object is empty, the content is in the blob
+	if key := attrs.Metadata[MetadataBlob]; key != "" {
+		obj = bucket.Object(blobObject(key))
+
+		if attrs, err = obj.Attrs(ctx); err != nil {
+			utils.LogErr(client.Close())
+			return nil, 0, err
+		}
+	}
+
+	// read the same generation as metadata, in case object is replaced while reading
+	reader, err := obj.Generation(attrs.Generation).NewReader(ctx)
+	if err != nil {
+		utils.LogErr(client.Close())
+		return nil, 0, err
+	}
+
+	if !IsEncrypted(attrs.Metadata) {
+		return &objectReader{
+			Reader:  reader,
+			closers: []io.Closer{reader, client},
+		}, reader.Attrs.Size, nil
+	}
+
+	defer func() {
+		utils.LogErr(reader.Close())
+		utils.LogErr(client.Close())
+	}()
+
+	cipherText, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fileByte, err := DecryptObject(attrs.Metadata, cipherText)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return writer.Close()
+	return io.NopCloser(bytes.NewReader(fileByte)), int64(len(fileByte)), nil
+}
+
+// ReEncryptObject rewrap data key of encrypted object with active master key,
+// or encrypt object that not encrypted yet. Reference object re-encrypt its blob instead,
+// and internal record is never encrypted since it's read without decryption. Return true if object is changed
+func ReEncryptObject(ctx context.Context, filePath string) (bool, error) {
+	if !EncryptionEnabled() {
+		return false, ErrorEncryptionKeyNotFound
+	}
+
+	scope, ok := reEncryptScope(filePath)
+	if !ok {
+		return false, nil
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	attrs, err := bucket.Object(filePath).Attrs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// reference object has no content
+	if key := attrs.Metadata[MetadataBlob]; key != "" {
+		filePath = blobObject(key)
+		scope = blobScope(filePath)
+	}
+
+	// metadata of blob can be changed by other reference at the same time
+	for attempt := 0; attempt < maxBlobRefAttempts; attempt++ {
+		changed, err := reEncryptObject(ctx, bucket.Object(filePath), scope)
+		if err == nil || !IsPreconditionFailed(err) {
+			return changed, err
+		}
+	}
+
+	return false, ErrorTooManyBlobRefChanges
+}
+
+// reEncryptScope return scope of object that can be re-encrypted, false for internal record.
+// Trash keep the scope of the user, and blob is shared between users so it's encrypted with its own scope
+func reEncryptScope(objectName string) (string, bool) {
+	name, isRecord := strings.CutPrefix(objectName, RecordPrefix)
+	if !isRecord {
+		return scopeOf(objectName), true
+	}
+
+	switch {
+	case strings.HasPrefix(name, blobPrefix):
+		return blobScope(objectName), true
+	case strings.HasPrefix(name, trashPrefix):
+		return scopeOf(strings.TrimPrefix(name, trashPrefix)), true
+	}
+
+	return "", false
+}
+
+func reEncryptObject(ctx context.Context, obj *storage.ObjectHandle, scope string) (bool, error) {
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if IsEncrypted(attrs.Metadata) {
+		if !NeedRewrap(attrs.Metadata, scope) {
+			return false, nil
+		}
+
+		encryptionMetadata, err := RewrapDataKey(attrs.Metadata, scope)
+		if err != nil {
+			return false, err
+		}
+
+		for key, value := range encryptionMetadata {
+			attrs.Metadata[key] = value
+		}
+
+		// only metadata is changed, since data key is the same
+		_, err = obj.If(storage.Conditions{MetagenerationMatch: attrs.Metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{
+			Metadata: attrs.Metadata,
+		})
+
+		return err == nil, err
+	}
+
+	reader, err := obj.Generation(attrs.Generation).NewReader(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	fileByte, err := io.ReadAll(reader)
+	utils.LogErr(reader.Close())
+	if err != nil {
+		return false, err
+	}
+
+	cipherText, encryptionMetadata, err := EncryptObject(scope, fileByte)
+	if err != nil {
+		return false, err
+	}
+
+	writer := obj.If(storage.Conditions{GenerationMatch: attrs.Generation, MetagenerationMatch: attrs.Metageneration}).NewWriter(ctx)
+	writer.ContentType = attrs.ContentType
+	writer.Metadata = attrs.Metadata
+	for key, value := range encryptionMetadata {
+		writer.Metadata[key] = value
+	}
+
+	if _, err = writer.Write(cipherText); err != nil {
+		return false, err
+	}
+
+	return true, writer.Close()
+}
+
+// ReEncryptObjects re-encrypt all object with prefix, return total object that changed.
+// Internal record is skipped, except blob and trash that contain file content
+func ReEncryptObjects(ctx context.Context, prefix string) (int, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		objects     = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Objects(ctx, &storage.Query{Prefix: prefix})
+		objectNames = make([]string, 0)
+		total       = new(atomic.Int64)
+	)
+
+	for {
+		obj, err := objects.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			return 0, err
+		}
+
+		if _, ok := reEncryptScope(obj.Name); ok {
+			objectNames = append(objectNames, obj.Name)
+		}
+	}
+
+	err = Batch(ctx, objectNames, func(ctx context.Context, _ int, objectName string) error {
+		changed, err := ReEncryptObject(ctx, objectName)
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			total.Add(1)
+		}
+		return nil
+	})
+
+	return int(total.Load()), err
+}
+
+// ConsumeDownload increase download count of object atomically, return true when it's the last allowed download,
+// so the object must be deleted after it's served
+func ConsumeDownload(ctx context.Context, filePath string) (bool, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	obj := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(filePath)
+
+	// retry when other download change the counter at the same time
+	for attempt := 0; attempt < maxConsumeDownloadAttempts; attempt++ {
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		fileData := new(models.DataFile)
+		if err = UnmarshalMetadata(attrs.Metadata, fileData); err != nil {
+			return false, err
+		}
+
+		if fileData.MaxDownloads == 0 {
+			return false, nil
+		}
+
+		if fileData.DownloadCount >= fileData.MaxDownloads {
+			return false, ErrorDownloadLimitReached
+		}
+
+		attrs.Metadata[MetadataDownloadCount] = fmt.Sprintf("%d", fileData.DownloadCount+1)
+
+		_, err = obj.If(storage.Conditions{MetagenerationMatch: attrs.Metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{
+			Metadata: attrs.Metadata,
+		})
+		if err == nil {
+			return fileData.DownloadCount+1 == fileData.MaxDownloads, nil
+		}
+
+		if !IsPreconditionFailed(err) {
+			return false, err
+		}
+	}
+
+	return false, errors.New("too_many_concurrent_download")
 }
 
 func DeleteObject(ctx context.Context, filePath string) error {
@@ -166,12 +547,27 @@ func DeleteObject(ctx context.Context, filePath string) error {
 		utils.LogErr(client.Close())
 	}()
 
-	obj := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(filePath)
+	var (
+		bucket = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		obj    = bucket.Object(filePath)
+	)
 
 	attrs, err := obj.Attrs(ctx)
 	if err != nil {
 		return err
 	}
 	obj = obj.If(storage.Conditions{GenerationMatch: attrs.Generation})
-	return obj.Delete(ctx)
+	if err = obj.Delete(ctx); err != nil {
+		return err
+	}
+
+	// blob is deleted only by the last reference
+	if key := attrs.Metadata[MetadataBlob]; key != "" {
+		if err = releaseBlob(ctx, bucket, key); err != nil {
+			return err
+		}
+	}
+
+	// versions never outlive the file
+	return deleteVersions(ctx, bucket, filePath)
 }
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"errors"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -100,13 +101,15 @@ func TestGetObject(test *testing.T) {
 		log.SetOutput(os.Stdout)
 		log.Info(fileData.Url)
 
-		agent := fiber.Get(fileData.Url)
+		downloadUrl, err := url.Parse(fileData.Url)
+		require.NoError(test, err)
+		assert.Equal(test, "/files/testgetobject/download/ok.txt", downloadUrl.Path)
+		assert.NotContains(test, fileData.Url, os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		require.NoError(test, VerifyDownloadSignature(filePath, fileData.FileId, downloadUrl.Query().Get("exp"), downloadUrl.Query().Get("sig")))
 
-		statusCode, body, errs := agent.Bytes()
-		require.Empty(test, errs)
-		assert.Equal(test, fiber.StatusOK, statusCode)
-		assert.NotEmpty(test, body)
-		assert.Equal(test, objByte, body)
+		fileByte, err := ReadObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.Equal(test, objByte, fileByte)
 
 		Format(fileData)
 		assert.Less(test, fileData.UploadedAt, time.Now().UnixMilli())
@@ -189,3 +192,141 @@ func TestDeleteObject(test *testing.T) {
 		assert.True(test, errors.Is(err, storage.ErrObjectNotExist))
 	})
 }
+
+func TestReadObject(test *testing.T) {
+	var (
+		filePath = strings.ToLower(test.Name()) + "/read.txt"
+		objByte  = []byte("is ok")
+		key      = newMasterKey(test, "test")
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+	})
+
+	test.Setenv("STORAGE_ENCRYPTION_KEYS", key)
+
+	require.NoError(test, UploadObject(storeCtx, filePath, objByte, &models.DataFile{
+		AutoDeleteAt:      time.Now().Add(2 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 30, // 30 seconds
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+	}))
+
+	test.Run("TestOkEncrypted", func(test *testing.T) {
+		fileByte, err := ReadObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.Equal(test, objByte, fileByte)
+
+		fileData, err := GetObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.Equal(test, int64(len(objByte)), fileData.Size)
+		assert.Contains(test, fileData.Url, "/download/read.txt?")
+	})
+
+	test.Run("TestReEncrypt", func(test *testing.T) {
+		test.Setenv("STORAGE_ENCRYPTION_KEYS", newMasterKey(test, "new")+","+key)
+
+		changed, err := ReEncryptObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.True(test, changed)
+
+		changed, err = ReEncryptObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.False(test, changed)
+
+		fileByte, err := ReadObject(storeCtx, filePath)
+		require.NoError(test, err)
+		assert.Equal(test, objByte, fileByte)
+	})
+
+	test.Run("TestNotFound", func(test *testing.T) {
+		fileByte, err := ReadObject(storeCtx, "not_found.txt")
+		require.ErrorIs(test, err, storage.ErrObjectNotExist)
+		assert.Empty(test, fileByte)
+	})
+}
+
+func TestReEncryptScope(test *testing.T) {
+	for objectName, scope := range map[string]string{
+		"user/file.txt":                    "user",
+		RecordPrefix + blobPrefix + "abc":  "blobs/abc",
+		RecordPrefix + trashPrefix + "u/x": "u",
+	} {
+		actual, ok := reEncryptScope(objectName)
+		assert.True(test, ok, objectName)
+		assert.Equal(test, scope, actual, objectName)
+	}
+
+	for _, objectName := range []string{RecordPrefix + shareRecordPrefix + "id", RecordPrefix + versionPrefix + "u/x/1", RecordPrefix + pendingUploadPrefix + "id"} {
+		_, ok := reEncryptScope(objectName)
+		assert.False(test, ok, objectName)
+	}
+}
+
+func TestReEncryptObjects(test *testing.T) {
+	var (
+		name   = strings.ToLower(test.Name()) + "/record"
+		record = map[string]string{"name": "is ok"}
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteRecord(storeCtx, name))
+	})
+
+	require.NoError(test, WriteRecord(storeCtx, name, record, RecordOverwrite, nil))
+
+	test.Setenv("STORAGE_ENCRYPTION_KEYS", newMasterKey(test, "test"))
+
+	total, err := ReEncryptObjects(storeCtx, RecordPrefix+name)
+	require.NoError(test, err)
+	assert.Zero(test, total)
+
+	actual := make(map[string]string)
+	_, err = ReadRecord(storeCtx, name, &actual)
+	require.NoError(test, err)
+	assert.Equal(test, record, actual)
+}
+
+func TestConsumeDownload(test *testing.T) {
+	filePath := strings.ToLower(test.Name()) + "/burn.txt"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+	})
+
+	require.NoError(test, UploadObject(storeCtx, filePath, []byte("is ok"), &models.DataFile{
+		AutoDeleteAt:      time.Now().Add(2 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 30, // 30 seconds
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+		MaxDownloads:      2,
+		DownloadCount:     100, // must be ignored
+	}))
+
+	isLast, err := ConsumeDownload(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.False(test, isLast)
+
+	isLast, err = ConsumeDownload(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.True(test, isLast)
+
+	fileData, err := GetObject(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Equal(test, uint(2), fileData.DownloadCount)
+	assert.Equal(test, uint(2), fileData.MaxDownloads)
+
+	_, err = ConsumeDownload(storeCtx, filePath)
+	require.ErrorIs(test, err, ErrorDownloadLimitReached)
+}
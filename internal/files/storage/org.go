@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// OrgPrefix namespace of organization `org:<name>`, username never contain colon so it's never collide
+	OrgPrefix        = "org:"
+	OrgRoleOwner     = "owner"
+	OrgRoleWriter    = "writer"
+	OrgRoleReader    = "reader"
+	HeaderOrgRole    = "member-role"
+	MaxOrgAutoDelete = 8766 * time.Hour // 1 year, the same as file auto delete
+	// LocalOrg key of organization in fiber locals, when namespace of request is organization
+	LocalOrg             = "org"
+	orgRecordPrefix      = "orgs/"
+	maxUpdateOrgAttempts = 10
+)
+
+var (
+	ErrorOrgNotFound      = errors.New("org_not_found")
+	ErrorInvalidOrgName   = errors.New("org_name_must_be_3_to_63_lowercase_letters_numbers_or_dash")
+	ErrorInvalidOrgRole   = errors.New("org_role_must_be_owner_writer_or_reader")
+	ErrorInvalidOrgPolicy = errors.New("org_quota_must_be_positive_and_default_auto_delete_in_must_be_less_than_1_year")
+	ErrorLastOrgOwner     = errors.New("org_must_have_at_least_one_owner")
+	ErrorQuotaExceeded    = errors.New("org_quota_exceeded")
+
+	orgNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+	orgRoleRank    = map[string]int{OrgRoleReader: 1, OrgRoleWriter: 2, OrgRoleOwner: 3}
+)
+
+// OrgName return name of organization from namespace, false when namespace is user
+func OrgName(namespace string) (string, bool) {
+	if !strings.HasPrefix(namespace, OrgPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(namespace, OrgPrefix), true
+}
+
+func ValidateOrgName(name string) error {
+	if !orgNamePattern.MatchString(name) {
+		return ErrorInvalidOrgName
+	}
+
+	return nil
+}
+
+func ValidateOrgRole(role string) error {
+	if _, ok := orgRoleRank[role]; !ok {
+		return ErrorInvalidOrgRole
+	}
+
+	return nil
+}
+
+// ValidateOrgPolicy check quota and default auto delete of organization
+func ValidateOrgPolicy(org *models.Org) error {
+	if org.MaxFiles < 0 || org.MaxBytes < 0 || org.DefaultAutoDeleteIn < 0 || org.DefaultAutoDeleteIn >= MaxOrgAutoDelete.Milliseconds() {
+		return ErrorInvalidOrgPolicy
+	}
+
+	return nil
+}
+
+// OrgRoleAllows check role of member is enough for required role, owner can write and writer can read
+func OrgRoleAllows(role, required string) bool {
+	return orgRoleRank[role] > 0 && orgRoleRank[role] >= orgRoleRank[required]
+}
+
+// OrgFromCtx return organization that is loaded by middleware, nil when namespace of request is user
+func OrgFromCtx(ctx *fiber.Ctx) *models.Org {
+	org, _ := ctx.Locals(LocalOrg).(*models.Org)
+	return org
+}
+
+// CreateOrg store new organization, it's failed with precondition error when the name is already used
+func CreateOrg(ctx context.Context, org *models.Org) error {
+	org.CreatedAt = time.Now().UnixMilli()
+	org.Usage = nil
+
+	return WriteRecord(ctx, orgRecordPrefix+org.Name, org, RecordMustNotExist, nil)
+}
+
+func GetOrg(ctx context.Context, name string) (*models.Org, error) {
+	if ValidateOrgName(name) != nil {
+		return nil, ErrorOrgNotFound
+	}
+
+	org := new(models.Org)
+	if _, err := ReadRecord(ctx, orgRecordPrefix+name, org); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrorOrgNotFound
+		}
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// UpdateOrg change organization with update atomically, it's retried when other request change it at the same time
+func UpdateOrg(ctx context.Context, name string, update func(org *models.Org) error) (*models.Org, error) {
+	for attempt := 0; attempt < maxUpdateOrgAttempts; attempt++ {
+		org := new(models.Org)
+		generation, err := ReadRecord(ctx, orgRecordPrefix+name, org)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return nil, ErrorOrgNotFound
+			}
+			return nil, err
+		}
+
+		if err = update(org); err != nil {
+			return nil, err
+		}
+
+		if !hasOrgOwner(org) {
+			return nil, ErrorLastOrgOwner
+		}
+
+		org.Usage = nil
+		err = WriteRecord(ctx, orgRecordPrefix+name, org, generation, nil)
+		if err == nil {
+			return org, nil
+		}
+
+		if !IsPreconditionFailed(err) {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("too_many_concurrent_update")
+}
+
+// DeleteOrg delete organization after its trash and versions is deleted permanently,
+// so files of deleted organization is never exposed when the name is used again
+func DeleteOrg(ctx context.Context, name string) error {
+	if err := SweepTrash(ctx, OrgPrefix+name, true); err != nil {
+		return err
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	// versions left by file that is deleted while it is updated
+	if err = deleteVersions(ctx, client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")), OrgPrefix+name); err != nil {
+		return err
+	}
+
+	if err = DeleteRecord(ctx, orgRecordPrefix+name); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorOrgNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func hasOrgOwner(org *models.Org) bool {
+	for _, role := range org.Members {
+		if role == OrgRoleOwner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetOrgUsage count files and size of organization
+func GetOrgUsage(ctx context.Context, name string) (*models.OrgUsage, error) {
+	filesData, err := ListObjects(ctx, OrgPrefix+name+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.OrgUsage{Files: len(filesData)}
+	for _, fileData := range filesData {
+		usage.Bytes += fileData.Size
+	}
+
+	return usage, nil
+}
+
+// CheckOrgQuota return ErrorQuotaExceeded when files and size is added to organization more than its quota,
+// size can be negative when file is replaced by smaller one. It's always allowed for user namespace (org is nil)
+func CheckOrgQuota(ctx context.Context, org *models.Org, files int, size int64) error {
+	if org == nil || (org.MaxFiles == 0 && org.MaxBytes == 0) {
+		return nil
+	}
+
+	usage, err := GetOrgUsage(ctx, org.Name)
+	if err != nil {
+		return err
+	}
+
+	if (org.MaxFiles > 0 && files > 0 && usage.Files+files > org.MaxFiles) || (org.MaxBytes > 0 && size > 0 && usage.Bytes+size > org.MaxBytes) {
+		return ErrorQuotaExceeded
+	}
+
+	return nil
+}
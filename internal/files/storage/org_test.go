@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgName(test *testing.T) {
+	name, ok := OrgName("org:my-team")
+	assert.True(test, ok)
+	assert.Equal(test, "my-team", name)
+
+	_, ok = OrgName("afifurrohman-id-gmail-com")
+	assert.False(test, ok)
+}
+
+func TestValidateOrg(test *testing.T) {
+	for _, name := range []string{"abc", "my-team", "team-2024"} {
+		assert.NoError(test, ValidateOrgName(name), name)
+	}
+
+	for _, name := range []string{"", "ab", "-team", "team-", "My-Team", "my_team", "my.team", "org:team"} {
+		assert.ErrorIs(test, ValidateOrgName(name), ErrorInvalidOrgName, name)
+	}
+
+	assert.NoError(test, ValidateOrgRole(OrgRoleWriter))
+	assert.ErrorIs(test, ValidateOrgRole("admin"), ErrorInvalidOrgRole)
+
+	assert.NoError(test, ValidateOrgPolicy(&models.Org{MaxFiles: 10, DefaultAutoDeleteIn: time.Hour.Milliseconds()}))
+	assert.ErrorIs(test, ValidateOrgPolicy(&models.Org{MaxBytes: -1}), ErrorInvalidOrgPolicy)
+	assert.ErrorIs(test, ValidateOrgPolicy(&models.Org{DefaultAutoDeleteIn: MaxOrgAutoDelete.Milliseconds()}), ErrorInvalidOrgPolicy)
+}
+
+func TestOrgRoleAllows(test *testing.T) {
+	assert.True(test, OrgRoleAllows(OrgRoleOwner, OrgRoleWriter))
+	assert.True(test, OrgRoleAllows(OrgRoleWriter, OrgRoleReader))
+	assert.False(test, OrgRoleAllows(OrgRoleReader, OrgRoleWriter))
+	assert.False(test, OrgRoleAllows("", OrgRoleReader))
+}
+
+func TestUpdateOrg(test *testing.T) {
+	const name = "testupdateorg"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		assert.NoError(test, DeleteOrg(storeCtx, name))
+	})
+
+	require.NoError(test, CreateOrg(storeCtx, &models.Org{
+		Name:    name,
+		Members: map[string]string{"owner": OrgRoleOwner},
+	}))
+	require.True(test, IsPreconditionFailed(CreateOrg(storeCtx, &models.Org{Name: name})))
+
+	org, err := UpdateOrg(storeCtx, name, func(org *models.Org) error {
+		org.Members["member"] = OrgRoleReader
+		return nil
+	})
+	require.NoError(test, err)
+	assert.Len(test, org.Members, 2)
+
+	_, err = UpdateOrg(storeCtx, name, func(org *models.Org) error {
+		delete(org.Members, "owner")
+		return nil
+	})
+	assert.ErrorIs(test, err, ErrorLastOrgOwner)
+
+	org, err = GetOrg(storeCtx, name)
+	require.NoError(test, err)
+	assert.Equal(test, OrgRoleOwner, org.Members["owner"])
+
+	// empty organization never exceed its quota
+	assert.NoError(test, CheckOrgQuota(storeCtx, &models.Org{Name: name, MaxFiles: 1}, 1, 0))
+	assert.ErrorIs(test, CheckOrgQuota(storeCtx, &models.Org{Name: name, MaxFiles: 1}, 2, 0), ErrorQuotaExceeded)
+}
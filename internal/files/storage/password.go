@@ -0,0 +1,76 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HeaderPassword       = "file-password"
+	MetadataPasswordHash = "file-password-hash" // salted hash of file password, it's not exposed as file header
+	UnlockCookieName     = "tempsy_unlock"
+	UnlockCookieExpires  = 15 * time.Minute
+	MinPasswordLength    = 6
+	MaxPasswordLength    = 72 // bcrypt only use the first 72 bytes
+	// LocalInvalidPassword key in fiber locals, set when wrong password is sent, so only wrong password is rate limited
+	LocalInvalidPassword = "invalid-password"
+)
+
+// HashPassword return salted hash of password, or empty string when password is empty (no password)
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("file_password_must_be_between_%d_and_%d_bytes", MinPasswordLength, MaxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func VerifyPassword(passwordHash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
+
+// SignUnlockToken create token for unlock cookie in format `<expires-unix>.<signature>`,
+// signature is bound to password hash, so the token is invalid when password is changed
+func SignUnlockToken(filePath, passwordHash string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return exp + "." + unlockSignature(filePath, passwordHash, exp)
+}
+
+func VerifyUnlockToken(token, filePath, passwordHash string) bool {
+	split := strings.SplitN(token, ".", 2)
+	if len(split) != 2 {
+		return false
+	}
+
+	exp, err := strconv.ParseInt(split[0], 10, 64)
+	if err != nil || exp < time.Now().Unix() {
+		return false
+	}
+
+	return hmac.Equal([]byte(split[1]), []byte(unlockSignature(filePath, passwordHash, split[0])))
+}
+
+func unlockSignature(filePath, passwordHash, exp string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET_KEY")))
+	mac.Write([]byte(strings.Join([]string{filePath, passwordHash, exp}, "\n")))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
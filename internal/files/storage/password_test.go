@@ -0,0 +1,96 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashPassword(test *testing.T) {
+	test.Run("TestOk", func(test *testing.T) {
+		hash, err := HashPassword("secret-password")
+		require.NoError(test, err)
+
+		assert.NotContains(test, hash, "secret-password")
+		assert.True(test, VerifyPassword(hash, "secret-password"))
+		assert.False(test, VerifyPassword(hash, "wrong-password"))
+	})
+
+	test.Run("TestOkEmptyPassword", func(test *testing.T) {
+		hash, err := HashPassword("")
+		require.NoError(test, err)
+		assert.Empty(test, hash)
+	})
+
+	test.Run("TestOnInvalidLength", func(test *testing.T) {
+		for _, password := range []string{"short", strings.Repeat("a", MaxPasswordLength+1)} {
+			hash, err := HashPassword(password)
+			require.Error(test, err)
+			assert.Empty(test, hash)
+		}
+	})
+}
+
+func TestVerifyUnlockToken(test *testing.T) {
+	test.Setenv("JWT_SECRET_KEY", "unlock-test-secret")
+
+	const (
+		filePath     = "test/secret.txt"
+		passwordHash = "$2a$10$hash"
+	)
+	token := SignUnlockToken(filePath, passwordHash, time.Now().Add(1*time.Minute))
+
+	tableTests := []struct {
+		name         string
+		token        string
+		filePath     string
+		passwordHash string
+		expected     bool
+	}{
+		{
+			name:         "TestOk",
+			token:        token,
+			filePath:     filePath,
+			passwordHash: passwordHash,
+			expected:     true,
+		},
+		{
+			name:         "TestOnOtherFile",
+			token:        token,
+			filePath:     "test/other.txt",
+			passwordHash: passwordHash,
+		},
+		{
+			name:         "TestOnPasswordChanged",
+			token:        token,
+			filePath:     filePath,
+			passwordHash: "$2a$10$other",
+		},
+		{
+			name:         "TestOnExpired",
+			token:        SignUnlockToken(filePath, passwordHash, time.Now().Add(-1*time.Second)),
+			filePath:     filePath,
+			passwordHash: passwordHash,
+		},
+		{
+			name:         "TestOnTampered",
+			token:        "9999999999." + strings.SplitN(token, ".", 2)[1],
+			filePath:     filePath,
+			passwordHash: passwordHash,
+		},
+		{
+			name:         "TestOnEmpty",
+			filePath:     filePath,
+			passwordHash: passwordHash,
+		},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			assert.Equal(test, tt.expected, VerifyUnlockToken(tt.token, tt.filePath, tt.passwordHash))
+		})
+	}
+}
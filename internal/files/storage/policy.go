@@ -0,0 +1,170 @@
+package store
+
+import (
+	"mime"
+	"os"
+	"strings"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountTier string
+
+const (
+	AccountTierGuest AccountTier = "guest"
+	AccountTierUser  AccountTier = "user"
+)
+
+// DefaultAllowedContentType used when env `MIME_ALLOWLIST` is not set,
+// charset parameter is not needed, since it's matched by media type
+var DefaultAllowedContentType = []string{
+	fiber.MIMEApplicationJSON,
+	fiber.MIMETextHTML,
+	fiber.MIMETextPlain,
+	fiber.MIMETextJavaScript,
+	fiber.MIMEApplicationXML, // Standard
+	fiber.MIMETextXML,        // Common Major browsers
+	"text/csv",
+	"text/css",
+	"text/markdown",
+	"video/mpeg",
+	"video/mp4",
+	"audio/mpeg",
+	"application/epub+zip", // standard
+	"application/epub",     // Chrome
+	"image/gif",
+	"image/jpeg",
+	"image/heic",
+	"application/pdf",
+	"audio/wav",
+	"audio/ogg",
+	"image/png",
+	"application/font-woff",    // Chrome
+	"font/woff",                // standard
+	"font/woff2",               // standard
+	"application/x-compressed", // Chrome (7z, rar)
+	"application/x-7z-compressed",
+
+	// excel (xls, xlsx)
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	// word (doc, docx)
+	"application/msword",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	// powerpoint (ppt, pptx)
+	"application/vnd.ms-powerpoint",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+
+	"application/x-sh",
+	"image/svg+xml",
+	"application/x-tar",
+	"application/x-gzip", // common Major browsers
+	"application/gzip",   // standard
+	"image/webp",
+	"image/x-icon",             // Common Major browsers
+	"image/vnd.microsoft.icon", // standard
+	"image/avif",
+	"application/wasm",
+	"application/x-zip-compressed", // common Major browsers
+	"application/zip",              // standard
+}
+
+// MimePolicy list of content type pattern, can use wildcard (e.g. `image/*`)
+// and parameter (e.g. `text/plain; charset=utf-8`) to restrict charset
+type MimePolicy struct {
+	Tier  AccountTier `json:"tier"`
+	Allow []string    `json:"allow"`
+	Block []string    `json:"block"`
+}
+
+// AccountTierOf return account tier by username
+func AccountTierOf(username string) AccountTier {
+	if strings.HasPrefix(username, guest.UsernamePrefix) {
+		return AccountTierGuest
+	}
+
+	return AccountTierUser
+}
+
+// MimePolicyFor return active policy for account tier, configured by env `MIME_ALLOWLIST` and `MIME_BLOCKLIST`
+// (comma separated), and can be override per tier (e.g. `MIME_ALLOWLIST_GUEST`, `MIME_BLOCKLIST_USER`)
+func MimePolicyFor(tier AccountTier) *MimePolicy {
+	tierSuffix := "_" + strings.ToUpper(string(tier))
+
+	return &MimePolicy{
+		Tier:  tier,
+		Allow: envList("MIME_ALLOWLIST"+tierSuffix, envList("MIME_ALLOWLIST", DefaultAllowedContentType)),
+		Block: envList("MIME_BLOCKLIST"+tierSuffix, envList("MIME_BLOCKLIST", make([]string, 0))),
+	}
+}
+
+// Allows check content type is allowed and not blocked by policy
+func (policy *MimePolicy) Allows(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, pattern := range policy.Block {
+		if matchMimePattern(pattern, mediaType, params) {
+			return false
+		}
+	}
+
+	for _, pattern := range policy.Allow {
+		if matchMimePattern(pattern, mediaType, params) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchMimePattern(pattern, mediaType string, params map[string]string) bool {
+	patternType, patternParams, err := mime.ParseMediaType(pattern)
+	if err != nil {
+		return false
+	}
+
+	if patternType == "*" {
+		patternType = "*/*"
+	}
+
+	if patternType != "*/*" {
+		patternSplit := strings.SplitN(patternType, "/", 2)
+		typeSplit := strings.SplitN(mediaType, "/", 2)
+		if len(patternSplit) != 2 || len(typeSplit) != 2 || patternSplit[0] != typeSplit[0] {
+			return false
+		}
+
+		if patternSplit[1] != "*" && patternSplit[1] != typeSplit[1] {
+			return false
+		}
+	}
+
+	// only restrict parameter when pattern have parameter
+	for key, value := range patternParams {
+		if !strings.EqualFold(params[key], value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func envList(key string, fallback []string) []string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountTierOf(test *testing.T) {
+	assert.Equal(test, AccountTierGuest, AccountTierOf(guest.GenerateUsername()))
+	assert.Equal(test, AccountTierUser, AccountTierOf("afif-example-com"))
+}
+
+func TestMimePolicyFor(test *testing.T) {
+	test.Run("TestDefault", func(test *testing.T) {
+		test.Setenv("MIME_ALLOWLIST", "")
+		test.Setenv("MIME_BLOCKLIST", "")
+
+		policy := MimePolicyFor(AccountTierUser)
+		assert.Equal(test, AccountTierUser, policy.Tier)
+		assert.Equal(test, DefaultAllowedContentType, policy.Allow)
+		assert.Empty(test, policy.Block)
+	})
+
+	test.Run("TestTierOverride", func(test *testing.T) {
+		test.Setenv("MIME_ALLOWLIST", "image/*, text/plain")
+		test.Setenv("MIME_ALLOWLIST_GUEST", "text/plain")
+		test.Setenv("MIME_BLOCKLIST_GUEST", "image/svg+xml")
+
+		assert.Equal(test, []string{"image/*", "text/plain"}, MimePolicyFor(AccountTierUser).Allow)
+		assert.Empty(test, MimePolicyFor(AccountTierUser).Block)
+
+		guestPolicy := MimePolicyFor(AccountTierGuest)
+		assert.Equal(test, []string{"text/plain"}, guestPolicy.Allow)
+		assert.Equal(test, []string{"image/svg+xml"}, guestPolicy.Block)
+	})
+}
+
+func TestMimePolicyAllows(test *testing.T) {
+	policy := &MimePolicy{
+		Allow: []string{"image/*", fiber.MIMEApplicationJSON, "text/plain; charset=utf-8", "video/mp4"},
+		Block: []string{"image/svg+xml"},
+	}
+
+	tableTests := []struct {
+		name        string
+		contentType string
+		allowed     bool
+	}{
+		{"TestOkWildcard", "image/heic", true},
+		{"TestOkCaseInsensitive", "Image/PNG", true},
+		{"TestOkAnyCharset", "application/json; charset=iso-8859-1", true},
+		{"TestOkWithoutCharset", fiber.MIMEApplicationJSON, true},
+		{"TestOkSameCharset", "text/plain; charset=UTF-8", true},
+		{"TestOkExact", "video/mp4", true},
+		{"TestOnBlocked", "image/svg+xml", false},
+		{"TestOnDifferentCharset", "text/plain; charset=iso-8859-1", false},
+		{"TestOnMissingCharset", "text/plain", false},
+		{"TestOnNotAllowed", "text/html", false},
+		{"TestOnInvalid", "invalid", false},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			assert.Equal(test, tt.allowed, policy.Allows(tt.contentType))
+		})
+	}
+
+	test.Run("TestAllowAll", func(test *testing.T) {
+		assert.True(test, (&MimePolicy{Allow: []string{"*"}}).Allows("application/octet-stream"))
+		assert.True(test, (&MimePolicy{Allow: []string{"*/*"}}).Allows("video/webm"))
+	})
+}
@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+)
+
+const (
+	HeaderFileSize           = "file-size"
+	PresignExpires           = 15 * time.Minute
+	pendingUploadPrefix      = "uploads/"
+	pendingObjectPrefix      = "pending/" // should have bucket lifecycle rule, for upload that never finalized
+	headerContentLengthRange = "x-goog-content-length-range"
+)
+
+var (
+	ErrorUploadNotFound     = errors.New("upload_not_found_or_expired")
+	ErrorUploadNotCompleted = errors.New("file_is_not_uploaded_yet")
+	ErrorUploadInvalidSize  = errors.New("uploaded_file_size_is_empty_or_exceed_the_limit")
+)
+
+// PendingUpload validated upload that waiting for the client to upload directly to the bucket
+type PendingUpload struct {
+	Id           string            `json:"id"`
+	FilePath     string            `json:"filePath"`
+	DeclaredType string            `json:"declaredType"` // verified with magic bytes when finalized
+	Sha256       string            `json:"sha256"`       // declared by the client in hex, content is not read by the server
+	MimeType     string            `json:"mimeType"`
+	MaxSize      int64             `json:"maxSize"`
+	ExpiresAt    int64             `json:"expiresAt"` // in milliseconds
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// CreatePendingUpload store validated file metadata, and return presigned url to upload the file to pending object.
+// Sha256 of the file is declared by the client in hex, since it's used as blob key without reading the file
+func CreatePendingUpload(ctx context.Context, filePath string, fileData *models.DataFile, declaredType, sha256Hex string, maxSize int64) (*models.PresignedUpload, error) {
+	id, err := randomId(16)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := objectMetadata(fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		expires = time.Now().Add(PresignExpires)
+		upload  = &PendingUpload{
+			Id:           id,
+			FilePath:     filePath,
+			DeclaredType: declaredType,
+			Sha256:       sha256Hex,
+			MimeType:     fileData.MimeType,
+			MaxSize:      maxSize,
+			ExpiresAt:    expires.UnixMilli(),
+			Metadata:     metadata,
+		}
+		contentLengthRange = fmt.Sprintf("1,%d", maxSize)
+	)
+
+	if err = WriteRecord(ctx, pendingUploadPrefix+id, upload, RecordMustNotExist, nil); err != nil {
+		return nil, err
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	// content type and size is signed, so client cannot upload other than declared
+	url, err := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).SignedURL(pendingObject(id), &storage.SignedURLOptions{
+		Method:      http.MethodPut,
+		Scheme:      storage.SigningSchemeV4,
+		Expires:     expires,
+		ContentType: fileData.MimeType,
+		Headers:     []string{headerContentLengthRange + ":" + contentLengthRange},
+		Insecure:    os.Getenv("APP_ENV") != "production",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PresignedUpload{
+		Id:     id,
+		Url:    url,
+		Method: http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":           fileData.MimeType,
+			headerContentLengthRange: contentLengthRange,
+		},
+		ExpiresAt: upload.ExpiresAt,
+	}, nil
+}
+
+// GetPendingUpload return ErrorUploadNotFound when upload is not exists or expired
+func GetPendingUpload(ctx context.Context, id string) (*PendingUpload, error) {
+	if !validRandomId(id) {
+		return nil, ErrorUploadNotFound
+	}
+
+	upload := new(PendingUpload)
+	if _, err := ReadRecord(ctx, pendingUploadPrefix+id, upload); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrorUploadNotFound
+		}
+		return nil, err
+	}
+
+	if upload.ExpiresAt < time.Now().UnixMilli() {
+		DiscardPendingUpload(ctx, upload)
+		return nil, ErrorUploadNotFound
+	}
+
+	return upload, nil
+}
+
+// FinalizeUpload verify uploaded pending object, then move it to the file path as reference to the blob of its content.
+// Without encryption, it's copied inside the storage so the file is never read or uploaded again by the server,
+// and blob key is declared sha256 with md5 computed by the storage
+func FinalizeUpload(ctx context.Context, upload *PendingUpload, mimePolicy *MimePolicy) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		bucket  = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		pending = bucket.Object(pendingObject(upload.Id))
+	)
+
+	attrs, err := pending.Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorUploadNotCompleted
+		}
+		return err
+	}
+	pending = pending.Generation(attrs.Generation)
+
+	if attrs.Size < 1 || attrs.Size > upload.MaxSize {
+		DiscardPendingUpload(ctx, upload)
+		return ErrorUploadInvalidSize
+	}
+
+	fileData := &models.DataFile{MimeType: upload.MimeType}
+	if err = UnmarshalMetadata(upload.Metadata, fileData); err != nil {
+		return err
+	}
+	fileData.FileId = upload.Metadata[MetadataFileId]
+
+	// end-to-end encrypted file is cipher text, it's not verified
+	if fileData.E2eHeader == "" {
+		// only the first 512 bytes is used to detect content type
+		reader, err := pending.NewRangeReader(ctx, 0, 512)
+		if err != nil {
+			return err
+		}
+
+		head, err := io.ReadAll(reader)
+		utils.LogErr(reader.Close())
+		if err != nil {
+			return err
+		}
+
+		if fileData.MimeType, err = VerifyContentType(mimePolicy, upload.DeclaredType, head); err != nil {
+			DiscardPendingUpload(ctx, upload)
+			return err
+		}
+	}
+
+	if EncryptionEnabled() {
+		reader, err := pending.NewReader(ctx)
+		if err != nil {
+			return err
+		}
+
+		fileByte, err := io.ReadAll(reader)
+		utils.LogErr(reader.Close())
+		if err != nil {
+			return err
+		}
+
+		// the file is read anyway to be encrypted, so declared sha256 is verified
+		if blobHash(fileByte) != upload.Sha256 {
+			DiscardPendingUpload(ctx, upload)
+			return ErrorChecksumMismatch
+		}
+
+		if err = UploadObject(ctx, upload.FilePath, fileByte, fileData); err != nil {
+			return err
+		}
+	} else {
+		// object that is uploaded in single request always have md5
+		if len(attrs.MD5) != md5.Size {
+			return errors.New("uploaded_file_must_have_md5")
+		}
+
+		key := blobKey(upload.Sha256, attrs.MD5)
+		if err = acquireBlob(ctx, bucket, key, copyBlob(ctx, pending)); err != nil {
+			return err
+		}
+
+		if err = putReference(ctx, bucket, bucket.Object(upload.FilePath).If(storage.Conditions{DoesNotExist: true}), key, attrs.Size, attrs.CRC32C, fileData); err != nil {
+			return err
+		}
+	}
+
+	DiscardPendingUpload(ctx, upload)
+
+	return nil
+}
+
+// DiscardPendingUpload delete pending object and the record, error is only logged
+func DiscardPendingUpload(ctx context.Context, upload *PendingUpload) {
+	client, err := createClient(ctx)
+	if err != nil {
+		utils.LogErr(err)
+		return
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	if err = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(pendingObject(upload.Id)).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		utils.LogErr(err)
+	}
+
+	utils.LogErr(DeleteRecord(ctx, pendingUploadPrefix+upload.Id))
+}
+
+func pendingObject(id string) string {
+	return RecordPrefix + pendingObjectPrefix + id
+}
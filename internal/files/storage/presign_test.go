@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPendingUpload(test *testing.T) {
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+	defer cancel()
+
+	_, err := GetPendingUpload(storeCtx, "../invalid")
+	require.ErrorIs(test, err, ErrorUploadNotFound)
+}
+
+func TestFinalizeUpload(test *testing.T) {
+	const filePath = "testfinalizeupload/ok.txt"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	fileData := &models.DataFile{
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+		AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 10,
+	}
+
+	presigned, err := CreatePendingUpload(storeCtx, filePath, fileData, fiber.MIMETextPlainCharsetUTF8, blobHash([]byte("is ok")), 1024)
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+	})
+
+	assert.Equal(test, fiber.MethodPut, presigned.Method)
+	assert.Equal(test, "1,1024", presigned.Headers[headerContentLengthRange])
+	assert.Contains(test, presigned.Url, pendingObject(presigned.Id))
+
+	upload, err := GetPendingUpload(storeCtx, presigned.Id)
+	require.NoError(test, err)
+	require.Equal(test, filePath, upload.FilePath)
+
+	mimePolicy := MimePolicyFor(AccountTierUser)
+	require.ErrorIs(test, FinalizeUpload(storeCtx, upload, mimePolicy), ErrorUploadNotCompleted)
+
+	// simulate client upload with the presigned url
+	client, err := createClient(storeCtx)
+	require.NoError(test, err)
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	writer := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(pendingObject(upload.Id)).NewWriter(storeCtx)
+	_, err = writer.Write([]byte("is ok"))
+	require.NoError(test, err)
+	require.NoError(test, writer.Close())
+
+	require.NoError(test, FinalizeUpload(storeCtx, upload, mimePolicy))
+
+	dataFile, err := GetObject(storeCtx, filePath)
+	require.NoError(test, err)
+
+	assert.Equal(test, fiber.MIMETextPlainCharsetUTF8, dataFile.MimeType)
+	assert.NotEmpty(test, dataFile.FileId)
+	// the content is deduplicated like file uploaded through the server
+	assert.Equal(test, blobHash([]byte("is ok")), dataFile.Sha256)
+
+	attrs, err := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(filePath).Attrs(storeCtx)
+	require.NoError(test, err)
+	assert.Equal(test, contentBlobKey([]byte("is ok")), attrs.Metadata[MetadataBlob])
+
+	_, err = GetPendingUpload(storeCtx, upload.Id)
+	require.ErrorIs(test, err, ErrorUploadNotFound)
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// RecordPrefix internal object that not belong to user files, username that start with dot is rejected
+// by middleware.CheckFilesPath so it's never collide
+const RecordPrefix = ".tempsy/"
+
+// RecordMustNotExist generation for WriteRecord, record will be created only when not exists yet
+const (
+	RecordMustNotExist int64 = 0
+	RecordOverwrite    int64 = -1
+)
+
+// ReadRecord read JSON record into value, return generation of the record for conditional write
+func ReadRecord(ctx context.Context, name string, value any) (int64, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	reader, err := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(RecordPrefix + name).NewReader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		utils.LogErr(reader.Close())
+	}()
+
+	if err = json.NewDecoder(reader).Decode(value); err != nil {
+		return 0, err
+	}
+
+	return reader.Attrs.Generation, nil
+}
+
+// WriteRecord write value as JSON record, only when generation of existing record is match,
+// use RecordMustNotExist to create new record, or RecordOverwrite to write without condition
+func WriteRecord(ctx context.Context, name string, value any, generation int64, metadata map[string]string) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	recordByte, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	obj := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(RecordPrefix + name)
+
+	switch {
+	case generation == RecordMustNotExist:
+		obj = obj.If(storage.Conditions{DoesNotExist: true})
+	case generation > 0:
+		obj = obj.If(storage.Conditions{GenerationMatch: generation})
+	}
+
+	writer := obj.NewWriter(ctx)
+	writer.ContentType = fiber.MIMEApplicationJSON
+	writer.Metadata = metadata
+
+	if _, err = writer.Write(recordByte); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// ListRecords return name of records with prefix, filter by metadata is optional
+func ListRecords(ctx context.Context, prefix string, filter ...func(metadata map[string]string) bool) ([]string, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		objects = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Objects(ctx, &storage.Query{Prefix: RecordPrefix + prefix})
+		names   = make([]string, 0)
+	)
+
+	for {
+		obj, err := objects.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				return names, nil
+			}
+			return nil, err
+		}
+
+		if len(filter) > 0 && filter[0] != nil && !filter[0](obj.Metadata) {
+			continue
+		}
+
+		names = append(names, obj.Name[len(RecordPrefix):])
+	}
+}
+
+func DeleteRecord(ctx context.Context, name string) error {
+	return deleteRecordGeneration(ctx, name, RecordOverwrite)
+}
+
+func deleteRecordGeneration(ctx context.Context, name string, generation int64) error {
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	obj := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(RecordPrefix + name)
+	if generation > 0 {
+		obj = obj.If(storage.Conditions{GenerationMatch: generation})
+	}
+
+	return obj.Delete(ctx)
+}
+
+// IsPreconditionFailed check error is caused by generation or metageneration is not match,
+// it's mean object is changed by other request at the same time
+func IsPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
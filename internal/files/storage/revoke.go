@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	// RevocationCacheTTL how long token that is not revoked is trusted without reading the record,
+	// token that is revoked by this instance is rejected immediately
+	RevocationCacheTTL     = 1 * time.Minute
+	revocationRecordPrefix = "revoked/"
+)
+
+// RevocationRecord token id that cannot be used anymore, it's stored as record `revoked/<id>`
+type RevocationRecord struct {
+	Id        string `json:"id"`
+	RevokedAt int64  `json:"revokedAt"` // in milliseconds
+	ExpiresAt int64  `json:"expiresAt"` // in milliseconds, after the token is expired the record is not needed anymore
+}
+
+type revocationEntry struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+var revocationCache sync.Map
+
+// RevokeToken revoke all token with id (JWT `jti`), until expiresAt
+func RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	err := WriteRecord(ctx, revocationRecordPrefix+id, &RevocationRecord{
+		Id:        id,
+		RevokedAt: time.Now().UnixMilli(),
+		ExpiresAt: expiresAt.UnixMilli(),
+	}, RecordOverwrite, nil)
+	if err != nil {
+		return err
+	}
+
+	revocationCache.Store(id, &revocationEntry{revoked: true, checkedAt: time.Now()})
+	return nil
+}
+
+// IsTokenRevoked check token id is revoked, token that is not revoked is cached for RevocationCacheTTL,
+// and revoked token is cached without expiry since it's never allowed again, so claimed guest cost one read at most
+func IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	if value, ok := revocationCache.Load(id); ok {
+		entry := value.(*revocationEntry)
+		if entry.revoked || time.Since(entry.checkedAt) < RevocationCacheTTL {
+			return entry.revoked, nil
+		}
+	}
+
+	_, err := ReadRecord(ctx, revocationRecordPrefix+id, new(RevocationRecord))
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return false, err
+	}
+
+	revoked := err == nil
+	revocationCache.Store(id, &revocationEntry{revoked: revoked, checkedAt: time.Now()})
+
+	return revoked, nil
+}
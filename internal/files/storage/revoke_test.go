@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(test *testing.T) {
+	const id = "testrevoketoken"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		revocationCache.Delete(id)
+		assert.NoError(test, DeleteRecord(storeCtx, revocationRecordPrefix+id))
+	})
+
+	revoked, err := IsTokenRevoked(storeCtx, id)
+	require.NoError(test, err)
+	assert.False(test, revoked)
+
+	require.NoError(test, RevokeToken(storeCtx, id, time.Now().Add(1*time.Hour)))
+
+	// revoked by this instance is not waiting for cache
+	revoked, err = IsTokenRevoked(storeCtx, id)
+	require.NoError(test, err)
+	assert.True(test, revoked)
+
+	// other instance read the record
+	revocationCache.Delete(id)
+
+	revoked, err = IsTokenRevoked(storeCtx, id)
+	require.NoError(test, err)
+	assert.True(test, revoked)
+}
+
+func TestRevocationCache(test *testing.T) {
+	// storage is not reached, since the context is already canceled
+	storeCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	test.Run("TestOkNotRevoked", func(test *testing.T) {
+		const id = "testrevocationcachenotrevoked"
+		test.Cleanup(func() {
+			revocationCache.Delete(id)
+		})
+
+		revocationCache.Store(id, &revocationEntry{checkedAt: time.Now()})
+
+		revoked, err := IsTokenRevoked(storeCtx, id)
+		require.NoError(test, err)
+		assert.False(test, revoked)
+	})
+
+	test.Run("TestOkRevoked", func(test *testing.T) {
+		const id = "testrevocationcacherevoked"
+		test.Cleanup(func() {
+			revocationCache.Delete(id)
+		})
+
+		// revoked token is never allowed again, so it's not read again after TTL
+		revocationCache.Store(id, &revocationEntry{revoked: true, checkedAt: time.Now().Add(-2 * RevocationCacheTTL)})
+
+		revoked, err := IsTokenRevoked(storeCtx, id)
+		require.NoError(test, err)
+		assert.True(test, revoked)
+	})
+
+	test.Run("TestOnExpired", func(test *testing.T) {
+		const id = "testrevocationcacheexpired"
+		test.Cleanup(func() {
+			revocationCache.Delete(id)
+		})
+
+		revocationCache.Store(id, &revocationEntry{checkedAt: time.Now().Add(-2 * RevocationCacheTTL)})
+
+		_, err := IsTokenRevoked(storeCtx, id)
+		assert.Error(test, err)
+	})
+}
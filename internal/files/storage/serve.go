@@ -0,0 +1,59 @@
+package store
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slices"
+)
+
+// PublicContentSecurityPolicy sandbox public file, so active content cannot run script
+// or access anything else on the same origin even when it's opened inline
+const PublicContentSecurityPolicy = "sandbox; default-src 'none'; img-src 'self' data:; media-src 'self'; style-src 'unsafe-inline'"
+
+// ActiveContentType can execute script when rendered by browser
+var ActiveContentType = []string{
+	fiber.MIMETextHTML,
+	"application/xhtml+xml",
+	"image/svg+xml",
+	fiber.MIMETextJavaScript,
+	"application/javascript",
+	"application/wasm",
+	fiber.MIMETextXML,
+	fiber.MIMEApplicationXML,
+}
+
+// IsActiveContent check content type can execute script when rendered by browser
+func IsActiveContent(contentType string) bool {
+	return slices.Contains(ActiveContentType, MediaType(contentType))
+}
+
+// PublicDisposition return `attachment` for active content, unless env `PUBLIC_INLINE_ACTIVE_CONTENT` is true
+func PublicDisposition(contentType string) string {
+	if IsActiveContent(contentType) {
+		if inline, _ := strconv.ParseBool(os.Getenv("PUBLIC_INLINE_ACTIVE_CONTENT")); !inline {
+			return "attachment"
+		}
+	}
+
+	return "inline"
+}
+
+// PublicServerUrl return separate user content domain from env `USER_CONTENT_URL` if configured,
+// so public file is not served from the same origin as the API
+func PublicServerUrl() string {
+	if userContentUrl := os.Getenv("USER_CONTENT_URL"); userContentUrl != "" {
+		return userContentUrl
+	}
+
+	return os.Getenv("SERVER_URL")
+}
+
+// SetPublicHeaders set headers for serving public file safely
+func SetPublicHeaders(ctx *fiber.Ctx, fileName, contentType string) {
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, ContentDisposition(PublicDisposition(contentType), fileName))
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	ctx.Set(fiber.HeaderContentSecurityPolicy, PublicContentSecurityPolicy)
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicDisposition(test *testing.T) {
+	tableTests := []struct {
+		name        string
+		contentType string
+		inline      string
+		expected    string
+	}{
+		{"TestHtml", fiber.MIMETextHTMLCharsetUTF8, "", "attachment"},
+		{"TestSvg", "image/svg+xml", "", "attachment"},
+		{"TestJavascript", fiber.MIMETextJavaScript, "false", "attachment"},
+		{"TestWasm", "application/wasm", "", "attachment"},
+		{"TestImage", "image/png", "", "inline"},
+		{"TestText", fiber.MIMETextPlainCharsetUTF8, "", "inline"},
+		{"TestInlineConfigured", fiber.MIMETextHTML, "true", "inline"},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			test.Setenv("PUBLIC_INLINE_ACTIVE_CONTENT", tt.inline)
+
+			assert.Equal(test, tt.expected, PublicDisposition(tt.contentType))
+		})
+	}
+}
+
+func TestPublicServerUrl(test *testing.T) {
+	test.Setenv("SERVER_URL", "https://api.example.com")
+
+	test.Run("TestDefault", func(test *testing.T) {
+		test.Setenv("USER_CONTENT_URL", "")
+		assert.Equal(test, "https://api.example.com", PublicServerUrl())
+	})
+
+	test.Run("TestUserContentDomain", func(test *testing.T) {
+		test.Setenv("USER_CONTENT_URL", "https://usercontent.example.com")
+		assert.Equal(test, "https://usercontent.example.com", PublicServerUrl())
+
+		dataFile := &models.DataFile{Name: "test/example.txt", IsPublic: true}
+		Format(dataFile)
+		assert.Equal(test, "https://usercontent.example.com/files/test/public/example.txt", dataFile.Url)
+	})
+}
+
+func TestSetPublicHeaders(test *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		SetPublicHeaders(ctx, "index.html", fiber.MIMETextHTMLCharsetUTF8)
+		return ctx.SendString("<html></html>")
+	})
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil), 1500*10) // 15 seconds
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		utils.LogErr(res.Body.Close())
+	})
+
+	assert.Equal(test, fiber.MIMETextHTMLCharsetUTF8, res.Header.Get(fiber.HeaderContentType))
+	assert.Equal(test, "nosniff", res.Header.Get(fiber.HeaderXContentTypeOptions))
+	assert.Equal(test, PublicContentSecurityPolicy, res.Header.Get(fiber.HeaderContentSecurityPolicy))
+	assert.Contains(test, res.Header.Get(fiber.HeaderContentDisposition), "attachment;")
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+)
+
+const (
+	HeaderShareExpiresAt    = "share-expires-at"
+	MetadataFileId          = "file-id" // random id of file, so share of deleted file is not valid for new file with the same name
+	shareRecordPrefix       = "shares/"
+	shareIndexPrefix        = "share-index/"
+	maxConsumeShareAttempts = 10
+)
+
+var ErrorShareNotFound = errors.New("share_not_found_or_expired")
+
+// ShareRecord share link that resolved through the server, it's stored as record `shares/<id>`,
+// so the link is resolved by single read. Share of file is listed by index `share-index/<username>/<filename>/<id>`
+type ShareRecord struct {
+	Id            string `json:"id"`
+	FilePath      string `json:"filePath"`
+	FileId        string `json:"fileId"`
+	PasswordHash  string `json:"passwordHash,omitempty"`
+	ExpiresAt     int64  `json:"expiresAt"`    // in milliseconds
+	MaxDownloads  uint   `json:"maxDownloads"` // 0 is unlimited
+	DownloadCount uint   `json:"downloadCount"`
+	CreatedAt     int64  `json:"createdAt"` // in milliseconds
+}
+
+func (record *ShareRecord) Share() *models.Share {
+	return &models.Share{
+		Id:                  record.Id,
+		Url:                 fmt.Sprintf("%s/s/%s", PublicServerUrl(), record.Id),
+		FileName:            record.FilePath[strings.Index(record.FilePath, "/")+1:],
+		ExpiresAt:           record.ExpiresAt,
+		MaxDownloads:        record.MaxDownloads,
+		DownloadCount:       record.DownloadCount,
+		IsPasswordProtected: record.PasswordHash != "",
+		CreatedAt:           record.CreatedAt,
+	}
+}
+
+func (record *ShareRecord) expired() bool {
+	return record.ExpiresAt < time.Now().UnixMilli() || (record.MaxDownloads > 0 && record.DownloadCount >= record.MaxDownloads)
+}
+
+// UnmarshalShareHeader parse share header, expires at is default to file auto delete at and cannot be later than it
+func UnmarshalShareHeader(fileHeader FileHeader, fileData *models.DataFile) (*ShareRecord, error) {
+	record := &ShareRecord{
+		FilePath:  fileData.Name,
+		FileId:    fileData.FileId,
+		ExpiresAt: fileData.AutoDeleteAt,
+	}
+
+	if value := fileHeader.Get(HeaderShareExpiresAt); value != "" {
+		expiresAt, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("share_expires_at_must_be_valid_integer")
+		}
+
+		record.ExpiresAt = expiresAt
+	}
+
+	if record.ExpiresAt <= time.Now().UnixMilli() || record.ExpiresAt > fileData.AutoDeleteAt {
+		return nil, errors.New("share_expires_at_must_be_in_the_future_and_not_later_than_file_auto_delete_at")
+	}
+
+	if value := fileHeader.Get(HeaderMaxDownloads); value != "" {
+		maxDownloads, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, errors.New("max_downloads_must_be_valid_positive_integer")
+		}
+
+		record.MaxDownloads = uint(maxDownloads)
+	}
+
+	passwordHash, err := HashPassword(fileHeader.Get(HeaderPassword))
+	if err != nil {
+		return nil, err
+	}
+	record.PasswordHash = passwordHash
+
+	return record, nil
+}
+
+// CreateShare store new share with random id, the id is not predictable
+func CreateShare(ctx context.Context, record *ShareRecord) error {
+	id, err := randomId(16)
+	if err != nil {
+		return err
+	}
+
+	record.Id = id
+	record.DownloadCount = 0
+	record.CreatedAt = time.Now().UnixMilli()
+
+	// index is written first, so share is never created without being listed for the owner
+	if err = WriteRecord(ctx, shareIndexName(record.FilePath, id), id, RecordMustNotExist, nil); err != nil {
+		return err
+	}
+
+	return WriteRecord(ctx, shareRecordPrefix+id, record, RecordMustNotExist, nil)
+}
+
+func shareIndexName(filePath, id string) string {
+	return shareIndexPrefix + filePath + "/" + id
+}
+
+// GetShare return ErrorShareNotFound when share is not exists, expired or reach download limit
+func GetShare(ctx context.Context, id string) (*ShareRecord, error) {
+	if !validRandomId(id) {
+		return nil, ErrorShareNotFound
+	}
+
+	record := new(ShareRecord)
+	if _, err := ReadRecord(ctx, shareRecordPrefix+id, record); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrorShareNotFound
+		}
+		return nil, err
+	}
+
+	if record.expired() {
+		utils.LogErr(deleteShare(ctx, record, RecordOverwrite))
+		return nil, ErrorShareNotFound
+	}
+
+	return record, nil
+}
+
+// ListShares return all share of file, including expired share that not cleaned up yet
+func ListShares(ctx context.Context, filePath string) ([]*ShareRecord, error) {
+	names, err := ListRecords(ctx, shareIndexName(filePath, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ShareRecord, 0, len(names))
+	for _, name := range names {
+		record := new(ShareRecord)
+		if _, err = ReadRecord(ctx, shareRecordPrefix+name[strings.LastIndex(name, "/")+1:], record); err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				// revoked while listing, or the index is left by failed revoke
+				utils.LogErr(DeleteRecord(ctx, name))
+				continue
+			}
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// RevokeShare delete share and its index, share record must be read first with GetShare
+func RevokeShare(ctx context.Context, record *ShareRecord) error {
+	if err := deleteShare(ctx, record, RecordOverwrite); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ErrorShareNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// deleteShare delete the record before the index, index without record is cleaned up by ListShares
+func deleteShare(ctx context.Context, record *ShareRecord, generation int64) error {
+	if err := deleteRecordGeneration(ctx, shareRecordPrefix+record.Id, generation); err != nil {
+		return err
+	}
+
+	if err := DeleteRecord(ctx, shareIndexName(record.FilePath, record.Id)); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		utils.LogErr(err)
+	}
+
+	return nil
+}
+
+// ConsumeShare increase download count of share atomically, share is revoked after the last allowed download
+func ConsumeShare(ctx context.Context, id string) error {
+	// retry when other download change the counter at the same time
+	for attempt := 0; attempt < maxConsumeShareAttempts; attempt++ {
+		record := new(ShareRecord)
+		generation, err := ReadRecord(ctx, shareRecordPrefix+id, record)
+		if err != nil {
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return ErrorShareNotFound
+			}
+			return err
+		}
+
+		if record.expired() {
+			return ErrorShareNotFound
+		}
+
+		if record.MaxDownloads == 0 {
+			return nil
+		}
+
+		record.DownloadCount++
+		if record.DownloadCount == record.MaxDownloads {
+			// delete with generation match, so only one download can take the last one
+			err = deleteShare(ctx, record, generation)
+		} else {
+			err = WriteRecord(ctx, shareRecordPrefix+id, record, generation, nil)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if !IsPreconditionFailed(err) {
+			return err
+		}
+	}
+
+	return errors.New("too_many_concurrent_download")
+}
+
+// randomId return base64url random string, byteLength 16 is 128 bit
+func randomId(byteLength int) (string, error) {
+	randomByte := make([]byte, byteLength)
+	if _, err := io.ReadFull(rand.Reader, randomByte); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomByte), nil
+}
+
+func validRandomId(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	_, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil
+}
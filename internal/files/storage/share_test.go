@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalShareHeader(test *testing.T) {
+	fileData := &models.DataFile{
+		Name:         "test/share.txt",
+		FileId:       "file-id",
+		AutoDeleteAt: time.Now().Add(1 * time.Hour).UnixMilli(),
+	}
+
+	test.Run("TestOkDefault", func(test *testing.T) {
+		record, err := UnmarshalShareHeader(FileHeader{}, fileData)
+		require.NoError(test, err)
+
+		assert.Equal(test, fileData.Name, record.FilePath)
+		assert.Equal(test, fileData.FileId, record.FileId)
+		assert.Equal(test, fileData.AutoDeleteAt, record.ExpiresAt)
+		assert.Zero(test, record.MaxDownloads)
+		assert.Empty(test, record.PasswordHash)
+	})
+
+	test.Run("TestOk", func(test *testing.T) {
+		expiresAt := time.Now().Add(1 * time.Minute).UnixMilli()
+
+		record, err := UnmarshalShareHeader(FileHeader{
+			HeaderShareExpiresAt: fmt.Sprintf("%d", expiresAt),
+			HeaderMaxDownloads:   "3",
+			HeaderPassword:       "secret-password",
+		}, fileData)
+		require.NoError(test, err)
+
+		assert.Equal(test, expiresAt, record.ExpiresAt)
+		assert.Equal(test, uint(3), record.MaxDownloads)
+		assert.True(test, VerifyPassword(record.PasswordHash, "secret-password"))
+	})
+
+	tableErrs := []struct {
+		name   string
+		header FileHeader
+	}{
+		{
+			name:   "TestOnInvalidExpiresAt",
+			header: FileHeader{HeaderShareExpiresAt: "invalid"},
+		},
+		{
+			name:   "TestOnExpiresAtInThePast",
+			header: FileHeader{HeaderShareExpiresAt: fmt.Sprintf("%d", time.Now().Add(-1*time.Minute).UnixMilli())},
+		},
+		{
+			name:   "TestOnExpiresAtLaterThanFile",
+			header: FileHeader{HeaderShareExpiresAt: fmt.Sprintf("%d", time.Now().Add(2*time.Hour).UnixMilli())},
+		},
+		{
+			name:   "TestOnInvalidMaxDownloads",
+			header: FileHeader{HeaderMaxDownloads: "-1"},
+		},
+		{
+			name:   "TestOnInvalidPassword",
+			header: FileHeader{HeaderPassword: "short"},
+		},
+	}
+
+	for _, tableE := range tableErrs {
+		test.Run(tableE.name, func(test *testing.T) {
+			record, err := UnmarshalShareHeader(tableE.header, fileData)
+			require.Error(test, err)
+			assert.Nil(test, record)
+		})
+	}
+}
+
+func TestShareRecord(test *testing.T) {
+	test.Setenv("USER_CONTENT_URL", "https://usercontent.example.com")
+
+	record := &ShareRecord{
+		Id:           "abc",
+		FilePath:     "test/share.txt",
+		ExpiresAt:    time.Now().Add(1 * time.Minute).UnixMilli(),
+		MaxDownloads: 1,
+	}
+
+	share := record.Share()
+	assert.Equal(test, "https://usercontent.example.com/s/abc", share.Url)
+	assert.Equal(test, "share.txt", share.FileName)
+	assert.False(test, share.IsPasswordProtected)
+	assert.False(test, record.expired())
+
+	record.DownloadCount = 1
+	assert.True(test, record.expired())
+
+	record.DownloadCount = 0
+	record.ExpiresAt = time.Now().Add(-1 * time.Second).UnixMilli()
+	assert.True(test, record.expired())
+}
+
+func TestRandomId(test *testing.T) {
+	id, err := randomId(16)
+	require.NoError(test, err)
+
+	assert.Len(test, id, 22)
+	assert.True(test, validRandomId(id))
+	assert.False(test, validRandomId(""))
+	assert.False(test, validRandomId("../test/share.txt"))
+}
+
+func TestConsumeShare(test *testing.T) {
+	filePath := strings.ToLower(test.Name()) + "/share.txt"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	fileData := &models.DataFile{
+		Name:              filePath,
+		AutoDeleteAt:      time.Now().Add(2 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 30, // 30 seconds
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+	}
+	require.NoError(test, UploadObject(storeCtx, filePath, []byte("is ok"), fileData))
+
+	record, err := UnmarshalShareHeader(FileHeader{HeaderMaxDownloads: "2"}, fileData)
+	require.NoError(test, err)
+	require.NoError(test, CreateShare(storeCtx, record))
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+		utils.LogErr(RevokeShare(storeCtx, record))
+	})
+
+	records, err := ListShares(storeCtx, filePath)
+	require.NoError(test, err)
+	require.Len(test, records, 1)
+	assert.Equal(test, record.Id, records[0].Id)
+
+	require.NoError(test, ConsumeShare(storeCtx, record.Id))
+
+	share, err := GetShare(storeCtx, record.Id)
+	require.NoError(test, err)
+	assert.Equal(test, uint(1), share.DownloadCount)
+
+	require.NoError(test, ConsumeShare(storeCtx, record.Id))
+
+	// revoked after the last download
+	_, err = GetShare(storeCtx, record.Id)
+	require.ErrorIs(test, err, ErrorShareNotFound)
+	require.ErrorIs(test, ConsumeShare(storeCtx, record.Id), ErrorShareNotFound)
+
+	// index is deleted with the share
+	records, err = ListShares(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Empty(test, records)
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorInvalidSignature = errors.New("invalid_download_url_signature")
+	ErrorSignatureExpired = errors.New("download_url_is_expired")
+)
+
+// DownloadUrl return url signed by the server to download file, filePath must be in format `username/filename`.
+// Signature is bound to file id, so the url is invalid when file is deleted
+func DownloadUrl(filePath, fileId string, expires time.Time) string {
+	split := strings.SplitN(filePath, "/", 2)
+	if len(split) < 2 {
+		return ""
+	}
+
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	return fmt.Sprintf("%s/files/%s/download/%s?%s", PublicServerUrl(), url.PathEscape(split[0]), url.PathEscape(split[1]), url.Values{
+		"exp": {exp},
+		"sig": {downloadSignature(filePath, fileId, exp)},
+	}.Encode())
+}
+
+// VerifyDownloadSignature verify `exp` and `sig` query of download url
+func VerifyDownloadSignature(filePath, fileId, exp, sig string) error {
+	if !hmac.Equal([]byte(sig), []byte(downloadSignature(filePath, fileId, exp))) {
+		return ErrorInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrorInvalidSignature
+	}
+
+	if expires < time.Now().Unix() {
+		return ErrorSignatureExpired
+	}
+
+	return nil
+}
+
+// downloadSecretKey from env `DOWNLOAD_URL_SECRET_KEY`, fallback to `JWT_SECRET_KEY`
+func downloadSecretKey() []byte {
+	if secretKey := os.Getenv("DOWNLOAD_URL_SECRET_KEY"); secretKey != "" {
+		return []byte(secretKey)
+	}
+
+	return []byte(os.Getenv("JWT_SECRET_KEY"))
+}
+
+func downloadSignature(filePath, fileId, exp string) string {
+	mac := hmac.New(sha256.New, downloadSecretKey())
+	mac.Write([]byte(strings.Join([]string{"download", filePath, fileId, exp}, "\n")))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
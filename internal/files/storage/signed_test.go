@@ -0,0 +1,103 @@
+package store
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadUrl(test *testing.T) {
+	test.Setenv("USER_CONTENT_URL", "https://usercontent.example.com")
+	test.Setenv("DOWNLOAD_URL_SECRET_KEY", "download-test-secret")
+
+	const (
+		filePath = "test/résumé 2024.docx"
+		fileId   = "file-id"
+	)
+
+	downloadUrl, err := url.Parse(DownloadUrl(filePath, fileId, time.Now().Add(1*time.Minute)))
+	require.NoError(test, err)
+
+	var (
+		exp = downloadUrl.Query().Get("exp")
+		sig = downloadUrl.Query().Get("sig")
+	)
+
+	assert.Equal(test, "usercontent.example.com", downloadUrl.Host)
+	assert.Equal(test, "/files/test/download/r%C3%A9sum%C3%A9%202024.docx", downloadUrl.EscapedPath())
+	assert.Empty(test, DownloadUrl("invalid", fileId, time.Now()))
+
+	tableTests := []struct {
+		name     string
+		filePath string
+		fileId   string
+		exp      string
+		sig      string
+		err      error
+	}{
+		{
+			name:     "TestOk",
+			filePath: filePath,
+			fileId:   fileId,
+			exp:      exp,
+			sig:      sig,
+		},
+		{
+			name:     "TestOnOtherFile",
+			filePath: "test/other.docx",
+			fileId:   fileId,
+			exp:      exp,
+			sig:      sig,
+			err:      ErrorInvalidSignature,
+		},
+		{
+			name:     "TestOnFileUploadedAgain",
+			filePath: filePath,
+			fileId:   "new-file-id",
+			exp:      exp,
+			sig:      sig,
+			err:      ErrorInvalidSignature,
+		},
+		{
+			name:     "TestOnExtendedExpires",
+			filePath: filePath,
+			fileId:   fileId,
+			exp:      "9999999999",
+			sig:      sig,
+			err:      ErrorInvalidSignature,
+		},
+		{
+			name:     "TestOnEmpty",
+			filePath: filePath,
+			fileId:   fileId,
+			err:      ErrorInvalidSignature,
+		},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			err := VerifyDownloadSignature(tt.filePath, tt.fileId, tt.exp, tt.sig)
+			if tt.err != nil {
+				require.ErrorIs(test, err, tt.err)
+			} else {
+				require.NoError(test, err)
+			}
+		})
+	}
+
+	test.Run("TestOnExpired", func(test *testing.T) {
+		expiredUrl, err := url.Parse(DownloadUrl(filePath, fileId, time.Now().Add(-1*time.Second)))
+		require.NoError(test, err)
+
+		require.ErrorIs(test, VerifyDownloadSignature(filePath, fileId, expiredUrl.Query().Get("exp"), expiredUrl.Query().Get("sig")), ErrorSignatureExpired)
+	})
+
+	test.Run("TestOnSecretKeyChanged", func(test *testing.T) {
+		test.Setenv("DOWNLOAD_URL_SECRET_KEY", "")
+
+		require.ErrorIs(test, VerifyDownloadSignature(filePath, fileId, exp, sig), ErrorInvalidSignature)
+	})
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slices"
+)
+
+type SniffPolicy string
+
+const (
+	SniffPolicyReject  SniffPolicy = "reject"  // reject file when content does not match declared type
+	SniffPolicyCorrect SniffPolicy = "correct" // replace declared type with detected type
+	SniffPolicyTrust   SniffPolicy = "trust"   // skip detection, trust declared type
+)
+
+var ErrorContentMismatch = errors.New("file_content_does_not_match_declared_content_type")
+
+// Detected type from http.DetectContentType that is equivalent with declared type
+var sniffAliases = [][]string{
+	{"image/x-icon", "image/vnd.microsoft.icon"},
+	{"application/gzip", "application/x-gzip"},
+	{"audio/wav", "audio/wave", "audio/x-wav"},
+	{"audio/ogg", "application/ogg", "video/ogg"},
+	{"font/woff", "application/font-woff"},
+	{"text/javascript", "application/javascript"},
+	// office open xml and epub are zip container
+	{
+		"application/zip",
+		"application/x-zip-compressed",
+		"application/epub+zip",
+		"application/epub",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+}
+
+// MediaType return lowercase media type without parameters (e.g. charset)
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+
+	return mediaType
+}
+
+// IsTextType is content type that can be detected as text by http.DetectContentType
+func IsTextType(contentType string) bool {
+	mediaType := MediaType(contentType)
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		slices.Contains([]string{fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, "application/javascript", "application/x-sh"}, mediaType)
+}
+
+// ContentSniffPolicy return policy for content type, can be override per type with env `CONTENT_SNIFF_OVERRIDES`
+// in format `type=policy,type=policy` (e.g. `text/csv=trust,image/svg+xml=correct`)
+func ContentSniffPolicy(contentType string) SniffPolicy {
+	mediaType := MediaType(contentType)
+
+	for _, override := range strings.Split(os.Getenv("CONTENT_SNIFF_OVERRIDES"), ",") {
+		if typePolicy := strings.SplitN(override, "=", 2); len(typePolicy) == 2 && MediaType(typePolicy[0]) == mediaType {
+			if policy := SniffPolicy(strings.TrimSpace(typePolicy[1])); policy.valid() {
+				return policy
+			}
+		}
+	}
+
+	if policy := SniffPolicy(os.Getenv("CONTENT_SNIFF_POLICY")); policy.valid() {
+		return policy
+	}
+
+	return SniffPolicyReject
+}
+
+func (policy SniffPolicy) valid() bool {
+	return policy == SniffPolicyReject || policy == SniffPolicyCorrect || policy == SniffPolicyTrust
+}
+
+// VerifyContentType detect content type of file with magic bytes and compare it with declared type,
+// return content type that should be stored, or ErrorContentMismatch if policy reject it
+func VerifyContentType(mimePolicy *MimePolicy, declared string, fileByte []byte) (string, error) {
+	policy := ContentSniffPolicy(declared)
+	if policy == SniffPolicyTrust {
+		return declared, nil
+	}
+
+	detected := http.DetectContentType(fileByte)
+	if sniffMatch(declared, detected) {
+		return declared, nil
+	}
+
+	// cannot correct to unknown binary or to type that not accepted
+	if policy == SniffPolicyCorrect && MediaType(detected) != fiber.MIMEOctetStream && mimePolicy.Allows(detected) {
+		return detected, nil
+	}
+
+	return "", ErrorContentMismatch
+}
+
+func sniffMatch(declared, detected string) bool {
+	var (
+		declaredType = MediaType(declared)
+		detectedType = MediaType(detected)
+	)
+
+	if declaredType == detectedType {
+		return true
+	}
+
+	// any text is fine for text type, since text type is not detected by magic bytes
+	if IsTextType(declaredType) {
+		return strings.HasPrefix(detectedType, "text/")
+	}
+
+	// http.DetectContentType does not know every binary format (e.g. 7z, ms office),
+	// unknown binary is fine as long as it's not detected as other type
+	if detectedType == fiber.MIMEOctetStream {
+		return true
+	}
+
+	for _, aliases := range sniffAliases {
+		if slices.Contains(aliases, declaredType) && slices.Contains(aliases, detectedType) {
+			return true
+		}
+	}
+
+	return false
+}
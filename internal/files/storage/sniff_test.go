@@ -0,0 +1,108 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	pngByte  = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+	htmlByte = []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+	zipByte  = []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00")
+)
+
+func TestVerifyContentType(test *testing.T) {
+	tableTests := []struct {
+		name      string
+		declared  string
+		file      []byte
+		policy    string
+		overrides string
+		expected  string
+		err       bool
+	}{
+		{
+			name:     "TestOkSameType",
+			declared: "image/png",
+			file:     pngByte,
+			expected: "image/png",
+		},
+		{
+			name:     "TestOkTextWithCharset",
+			declared: "application/json; charset=utf-8",
+			file:     []byte(`{"hello":"world"}`),
+			expected: "application/json; charset=utf-8",
+		},
+		{
+			name:     "TestOkZipContainer",
+			declared: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			file:     zipByte,
+			expected: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		},
+		{
+			name:     "TestOkUnknownBinary",
+			declared: "application/x-7z-compressed",
+			file:     []byte("7z\xBC\xAF\x27\x1C\x00\x04"),
+			expected: "application/x-7z-compressed",
+		},
+		{
+			name:     "TestOnHtmlAsImage",
+			declared: "image/png",
+			file:     htmlByte,
+			err:      true,
+		},
+		{
+			name:     "TestOnImageAsText",
+			declared: fiber.MIMETextPlain,
+			file:     pngByte,
+			err:      true,
+		},
+		{
+			name:     "TestOkCorrectPolicy",
+			declared: "image/png",
+			file:     htmlByte,
+			policy:   string(SniffPolicyCorrect),
+			expected: fiber.MIMETextHTMLCharsetUTF8,
+		},
+		{
+			name:      "TestOkTrustOverride",
+			declared:  "image/png",
+			file:      htmlByte,
+			overrides: "image/png=trust",
+			expected:  "image/png",
+		},
+		{
+			name:      "TestOnRejectOverride",
+			declared:  "image/png",
+			file:      htmlByte,
+			policy:    string(SniffPolicyCorrect),
+			overrides: "text/csv=trust, image/png=reject",
+			err:       true,
+		},
+	}
+
+	for _, tt := range tableTests {
+		test.Run(tt.name, func(test *testing.T) {
+			test.Setenv("CONTENT_SNIFF_POLICY", tt.policy)
+			test.Setenv("CONTENT_SNIFF_OVERRIDES", tt.overrides)
+
+			contentType, err := VerifyContentType(MimePolicyFor(AccountTierUser), tt.declared, tt.file)
+			if tt.err {
+				require.ErrorIs(test, err, ErrorContentMismatch)
+				assert.Empty(test, contentType)
+			} else {
+				require.NoError(test, err)
+				assert.Equal(test, tt.expected, contentType)
+			}
+		})
+	}
+}
+
+func TestMediaType(test *testing.T) {
+	assert.Equal(test, fiber.MIMEApplicationJSON, MediaType(fiber.MIMEApplicationJSONCharsetUTF8))
+	assert.Equal(test, "image/png", MediaType("IMAGE/PNG"))
+	assert.Equal(test, "text/plain", MediaType("text/plain; invalid"))
+}
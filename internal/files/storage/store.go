@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -12,72 +13,21 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
-	"github.com/gofiber/fiber/v2"
 	"google.golang.org/api/option"
 )
 
-var AcceptedContentType = []string{
-	fiber.MIMEApplicationJSONCharsetUTF8, // not sure
-	fiber.MIMEApplicationJSON,
-	fiber.MIMETextHTMLCharsetUTF8, // not sure
-	fiber.MIMETextHTML,
-	fiber.MIMETextPlainCharsetUTF8, // not sure
-	fiber.MIMETextPlain,
-	fiber.MIMETextJavaScriptCharsetUTF8, // not sure
-	fiber.MIMETextJavaScript,
-	fiber.MIMEApplicationXMLCharsetUTF8, // not sure
-	fiber.MIMEApplicationXML,            // Standard
-	fiber.MIMETextXML,                   // Common Major browsers
-	fiber.MIMETextXMLCharsetUTF8,        // not sure
-	"text/csv",
-	"text/css",
-	"video/mpeg",
-	"audio/mpeg",
-	"application/epub+zip", // standard
-	"application/epub",     // Chrome
-	"image/gif",
-	"image/jpeg",
-	"application/pdf",
-	"audio/wav",
-	"audio/ogg",
-	"image/png",
-	"application/font-woff",    // Chrome
-	"font/woff",                // standard
-	"font/woff2",               // standard
-	"application/x-compressed", // Chrome (7z, rar)
-	"application/x-7z-compressed",
-
-	// excel (xls, xlsx)
-	"application/vnd.ms-excel",
-	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
-	// word (doc, docx)
-	"application/msword",
-	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
-	// powerpoint (ppt, pptx)
-	"application/vnd.ms-powerpoint",
-	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
-
-	"application/x-sh",
-	"image/svg+xml",
-	"application/x-tar",
-	"application/x-gzip", // common Major browsers
-	"application/gzip",   // standard
-	"image/webp",
-	"image/x-icon",             // Common Major browsers
-	"image/vnd.microsoft.icon", // standard
-	"image/avif",
-	"application/wasm",
-	"application/x-zip-compressed", // common Major browsers
-	"application/zip",              // standard
-}
-
 // Since HTTP 1.1 is case insensitive, but we follow HTTP 2.0 standard which is lowercase
 const (
 	HeaderAutoDeleteAt      = "file-auto-delete-at"
 	HeaderPrivateUrlExpires = "file-private-url-expires"
 	HeaderIsPublic          = "file-is-public"
 	HeaderFileName          = "file-name"
+	HeaderE2eHeader         = "file-e2e-header" // opaque encryption header of end-to-end encrypted file
+	HeaderMaxDownloads      = "file-max-downloads"
+	MetadataDownloadCount   = "file-download-count" // managed by server, it's not accepted from file header
+	HeaderMaxVersions       = "file-max-versions"
 	DefaultTimeoutCtx       = 25 * time.Second
+	MaxE2eHeaderLength      = 1024
 )
 
 func createClient(ctx context.Context) (*storage.Client, error) {
@@ -124,9 +74,43 @@ func UnmarshalMetadata(metadata map[string]string, fileData *models.DataFile) er
 
 	}
 
+	// optional, only for end-to-end encrypted file
+	e2eHeader := metadata[HeaderE2eHeader]
+	if e2eHeader != "" && !validE2eHeader(e2eHeader) {
+		return fmt.Errorf("e2e_header_must_be_base64url_or_dot_and_max_%d_characters", MaxE2eHeaderLength)
+	}
+
+	// optional, 0 is unlimited and 1 is burn after read
+	var maxDownloads, downloadCount uint64
+	if metadata[HeaderMaxDownloads] != "" {
+		if maxDownloads, err = strconv.ParseUint(metadata[HeaderMaxDownloads], 10, 32); err != nil {
+			return errors.New("max_downloads_must_be_valid_positive_integer")
+		}
+	}
+
+	if metadata[MetadataDownloadCount] != "" {
+		if downloadCount, err = strconv.ParseUint(metadata[MetadataDownloadCount], 10, 32); err != nil {
+			return errors.New("download_count_must_be_valid_positive_integer")
+		}
+	}
+
+	// optional, 0 is no history and -1 keep all versions until the file is deleted
+	var maxVersions int64
+	if metadata[HeaderMaxVersions] != "" {
+		if maxVersions, err = strconv.ParseInt(metadata[HeaderMaxVersions], 10, 32); err != nil || maxVersions < -1 {
+			return errors.New("max_versions_must_be_valid_positive_integer_or_-1")
+		}
+	}
+
 	fileData.AutoDeleteAt = autoDeleteAt
 	fileData.PrivateUrlExpires = uint(privateUrlInt64)
 	fileData.IsPublic = isPublic
+	fileData.E2eHeader = e2eHeader
+	fileData.PasswordHash = metadata[MetadataPasswordHash]
+	fileData.IsPasswordProtected = fileData.PasswordHash != ""
+	fileData.MaxDownloads = uint(maxDownloads)
+	fileData.DownloadCount = uint(downloadCount)
+	fileData.MaxVersions = int(maxVersions)
 
 	return nil
 }
@@ -138,13 +122,33 @@ func Format(dataFile *models.DataFile) {
 		fileName := split[1]
 
 		if dataFile.IsPublic {
-			dataFile.Url = fmt.Sprintf("%s/files/%s/public/%s", os.Getenv("SERVER_URL"), split[0], fileName)
+			dataFile.Url = fmt.Sprintf("%s/files/%s/public/%s", PublicServerUrl(), url.PathEscape(split[0]), url.PathEscape(fileName))
+
+			// server never know the key, client must append it as url fragment `#<key>`
+			if dataFile.E2eHeader != "" {
+				dataFile.Url = fmt.Sprintf("%s/assets/download.html?username=%s&filename=%s", PublicServerUrl(), url.QueryEscape(split[0]), url.QueryEscape(fileName))
+			}
 		}
 
 		dataFile.Name = fileName
 	}
 }
 
+// header is opaque for the server, only allow characters that safe for object metadata
+func validE2eHeader(e2eHeader string) bool {
+	if len(e2eHeader) > MaxE2eHeaderLength {
+		return false
+	}
+
+	for _, char := range e2eHeader {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || strings.ContainsRune("-_.=", char)) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type FileHeader map[string]string
 
 // map http header to file header
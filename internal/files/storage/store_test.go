@@ -37,6 +37,59 @@ func TestUnmarshalMetadata(test *testing.T) {
 		assert.True(test, dataFile.IsPublic)
 	})
 
+	test.Run("TestOkE2eHeader", func(test *testing.T) {
+		e2eMetadata := map[string]string{
+			HeaderAutoDeleteAt:      metadata[HeaderAutoDeleteAt],
+			HeaderIsPublic:          metadata[HeaderIsPublic],
+			HeaderPrivateUrlExpires: metadata[HeaderPrivateUrlExpires],
+			HeaderE2eHeader:         "v1.q1XHz2aFkc0l0bVd",
+		}
+
+		e2eFile := new(models.DataFile)
+		require.NoError(test, UnmarshalMetadata(e2eMetadata, e2eFile))
+		assert.Equal(test, "v1.q1XHz2aFkc0l0bVd", e2eFile.E2eHeader)
+
+		e2eMetadata[HeaderE2eHeader] = "v1.<script>"
+		require.Error(test, UnmarshalMetadata(e2eMetadata, e2eFile))
+
+		e2eMetadata[HeaderE2eHeader] = strings.Repeat("a", MaxE2eHeaderLength+1)
+		require.Error(test, UnmarshalMetadata(e2eMetadata, e2eFile))
+	})
+
+	test.Run("TestOkMaxDownloads", func(test *testing.T) {
+		limitMetadata := map[string]string{
+			HeaderAutoDeleteAt:      metadata[HeaderAutoDeleteAt],
+			HeaderIsPublic:          metadata[HeaderIsPublic],
+			HeaderPrivateUrlExpires: metadata[HeaderPrivateUrlExpires],
+			HeaderMaxDownloads:      "1",
+			MetadataDownloadCount:   "0",
+		}
+
+		limitFile := new(models.DataFile)
+		require.NoError(test, UnmarshalMetadata(limitMetadata, limitFile))
+		assert.Equal(test, uint(1), limitFile.MaxDownloads)
+		assert.Equal(test, uint(0), limitFile.DownloadCount)
+
+		limitMetadata[HeaderMaxDownloads] = "-1"
+		require.Error(test, UnmarshalMetadata(limitMetadata, limitFile))
+	})
+
+	test.Run("TestOkMaxVersions", func(test *testing.T) {
+		versionMetadata := map[string]string{
+			HeaderAutoDeleteAt:      metadata[HeaderAutoDeleteAt],
+			HeaderIsPublic:          metadata[HeaderIsPublic],
+			HeaderPrivateUrlExpires: metadata[HeaderPrivateUrlExpires],
+			HeaderMaxVersions:       "-1",
+		}
+
+		versionFile := new(models.DataFile)
+		require.NoError(test, UnmarshalMetadata(versionMetadata, versionFile))
+		assert.Equal(test, -1, versionFile.MaxVersions)
+
+		versionMetadata[HeaderMaxVersions] = "-2"
+		require.Error(test, UnmarshalMetadata(versionMetadata, versionFile))
+	})
+
 	test.Run("TestInvalid", func(test *testing.T) {
 		test.Run("TestInvalidAutoDeleteAt", func(test *testing.T) {
 			metadata[HeaderAutoDeleteAt] = "invalid"
@@ -91,6 +144,19 @@ func TestFormat(test *testing.T) {
 		assert.NotContains(test, dataFile.Name, "/")
 	})
 
+	test.Run("TestFormatE2e", func(test *testing.T) {
+		e2eFile := &models.DataFile{
+			Name:      "test/example.bin",
+			IsPublic:  true,
+			E2eHeader: "v1.q1XHz2aFkc0l0bVd",
+		}
+
+		Format(e2eFile)
+
+		assert.Contains(test, e2eFile.Url, "/assets/download.html?username=test&filename=example.bin")
+		assert.Equal(test, "example.bin", e2eFile.Name)
+	})
+
 	test.Run("TestFormatOnInvalidName", func(test *testing.T) {
 		dataFile.Name = "test.txt"
 		before := *dataFile
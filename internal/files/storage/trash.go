@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"google.golang.org/api/iterator"
+)
+
+// Deleted file is moved to `.tempsy/trash/<username>/<generation>` with its versions,
+// so it can be restored until retention is passed
+const (
+	DefaultTrashRetention = 7 * 24 * time.Hour
+	metadataDeletedAt     = "file-deleted-at"
+	metadataTrashName     = "file-trash-name" // original file name
+	trashPrefix           = "trash/"
+)
+
+var ErrorTrashNotFound = errors.New("trash_not_found")
+
+// TrashRetention return env `TRASH_RETENTION` (e.g. `72h`), 0 disable trash so file is deleted permanently
+func TrashRetention() time.Duration {
+	retention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil || retention < 0 {
+		return DefaultTrashRetention
+	}
+
+	return retention
+}
+
+func trashObject(username, id string) string {
+	return trashObjectsPrefix(username) + id
+}
+
+func trashObjectsPrefix(username string) string {
+	return RecordPrefix + trashPrefix + username + "/"
+}
+
+// moveObject copy object to dst with additional metadata (empty value remove the metadata), then delete the object.
+// Blob reference is moved as well, so reference count is not changed
+func moveObject(ctx context.Context, bucket *storage.BucketHandle, attrs *storage.ObjectAttrs, dst string, metadata map[string]string) error {
+	var (
+		src           = bucket.Object(attrs.Name)
+		dstObj        = bucket.Object(dst).If(storage.Conditions{DoesNotExist: true})
+		copier        = dstObj.CopierFrom(src.Generation(attrs.Generation))
+		movedMetadata = make(map[string]string, len(attrs.Metadata)+len(metadata))
+	)
+
+	for key, value := range attrs.Metadata {
+		movedMetadata[key] = value
+	}
+	for key, value := range metadata {
+		if value == "" {
+			delete(movedMetadata, key)
+			continue
+		}
+		movedMetadata[key] = value
+	}
+
+	copier.ContentType = attrs.ContentType
+	copier.Metadata = movedMetadata
+
+	if _, err := copier.Run(ctx); err != nil {
+		return err
+	}
+
+	// object is changed while moved, so the copy is discarded
+	if err := src.If(storage.Conditions{GenerationMatch: attrs.Generation, MetagenerationMatch: attrs.Metageneration}).Delete(ctx); err != nil {
+		return errors.Join(err, bucket.Object(dst).Delete(ctx))
+	}
+
+	return nil
+}
+
+// moveVersions move versions of the file, together with the file
+func moveVersions(ctx context.Context, bucket *storage.BucketHandle, filePath, dst string) error {
+	names, err := versionNames(ctx, bucket, filePath)
+	if err != nil {
+		return err
+	}
+
+	return Batch(ctx, names, func(ctx context.Context, _ int, name string) error {
+		attrs, err := bucket.Object(name).Attrs(ctx)
+		if err != nil {
+			return err
+		}
+
+		return moveObject(ctx, bucket, attrs, versionsPrefix(dst)+strings.TrimPrefix(name, versionsPrefix(filePath)), nil)
+	})
+}
+
+// TrashObject move the file to trash, it's deleted permanently when trash is disabled
+func TrashObject(ctx context.Context, filePath string) error {
+	if TrashRetention() == 0 {
+		return DeleteObject(ctx, filePath)
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	attrs, err := bucket.Object(filePath).Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		split = strings.SplitN(filePath, "/", 2)
+		dst   = trashObject(split[0], fmt.Sprintf("%d", attrs.Generation))
+	)
+
+	err = moveObject(ctx, bucket, attrs, dst, map[string]string{
+		metadataDeletedAt: fmt.Sprintf("%d", time.Now().UnixMilli()),
+		metadataTrashName: split[1],
+	})
+	if err != nil {
+		return err
+	}
+
+	return moveVersions(ctx, bucket, filePath, dst)
+}
+
+func trashEntryOf(attrs *storage.ObjectAttrs) (*models.TrashEntry, error) {
+	deletedAt, err := strconv.ParseInt(attrs.Metadata[metadataDeletedAt], 10, 64)
+	if err != nil {
+		return nil, errors.New("deleted_at_must_be_valid_integer")
+	}
+
+	autoDeleteAt, err := strconv.ParseInt(attrs.Metadata[HeaderAutoDeleteAt], 10, 64)
+	if err != nil {
+		return nil, errors.New("auto_delete_at_must_be_valid_integer")
+	}
+
+	entry := &models.TrashEntry{
+		Id:        attrs.Name[strings.LastIndex(attrs.Name, "/")+1:],
+		Name:      attrs.Metadata[metadataTrashName],
+		MimeType:  attrs.ContentType,
+		Size:      attrs.Size,
+		DeletedAt: deletedAt,
+		PurgeAt:   time.UnixMilli(deletedAt).Add(TrashRetention()).UnixMilli(),
+	}
+
+	// temporary file never outlive its auto delete
+	if autoDeleteAt < entry.PurgeAt {
+		entry.PurgeAt = autoDeleteAt
+	}
+
+	if attrs.Metadata[MetadataBlob] != "" {
+		if entry.Size, err = strconv.ParseInt(attrs.Metadata[MetadataBlobSize], 10, 64); err != nil {
+			return nil, errors.New("blob_size_must_be_valid_integer")
+		}
+	} else if IsEncrypted(attrs.Metadata) {
+		entry.Size -= encryptionOverhead
+	}
+
+	return entry, nil
+}
+
+// ListTrash return deleted files of the user from the oldest
+func ListTrash(ctx context.Context, username string) ([]*models.TrashEntry, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		objects = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Objects(ctx, &storage.Query{Prefix: trashObjectsPrefix(username)})
+		entries = make([]*models.TrashEntry, 0)
+	)
+
+	for {
+		attrs, err := objects.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				return entries, nil
+			}
+			return nil, err
+		}
+
+		entry, err := trashEntryOf(attrs)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+}
+
+// RestoreTrash move deleted file back with its versions, return file path of restored file.
+// It's failed with precondition error when file with the same name already exists
+func RestoreTrash(ctx context.Context, username, id string) (string, error) {
+	if !validVersionId(id) {
+		return "", ErrorTrashNotFound
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	var (
+		bucket = client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+		src    = trashObject(username, id)
+	)
+
+	attrs, err := bucket.Object(src).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return "", ErrorTrashNotFound
+		}
+		return "", err
+	}
+
+	filePath := fmt.Sprintf("%s/%s", username, attrs.Metadata[metadataTrashName])
+
+	err = moveObject(ctx, bucket, attrs, filePath, map[string]string{
+		metadataDeletedAt: "",
+		metadataTrashName: "",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, moveVersions(ctx, bucket, src, filePath)
+}
+
+// PurgeTrash delete file in trash permanently
+func PurgeTrash(ctx context.Context, username, id string) error {
+	if !validVersionId(id) {
+		return ErrorTrashNotFound
+	}
+
+	err := DeleteObject(ctx, trashObject(username, id))
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return ErrorTrashNotFound
+	}
+
+	return err
+}
+
+// SweepTrash delete files in trash permanently when retention or its auto delete is passed,
+// all files is deleted when all is true
+func SweepTrash(ctx context.Context, username string, all bool) error {
+	entries, err := ListTrash(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	expired := make([]*models.TrashEntry, 0, len(entries))
+	for _, entry := range entries {
+		if all || entry.PurgeAt < time.Now().UnixMilli() {
+			expired = append(expired, entry)
+		}
+	}
+
+	return Batch(ctx, expired, func(ctx context.Context, _ int, entry *models.TrashEntry) error {
+		if err := PurgeTrash(ctx, username, entry.Id); err != nil && !errors.Is(err, ErrorTrashNotFound) {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashRetention(test *testing.T) {
+	test.Setenv("TRASH_RETENTION", "")
+	assert.Equal(test, DefaultTrashRetention, TrashRetention())
+
+	test.Setenv("TRASH_RETENTION", "72h")
+	assert.Equal(test, 72*time.Hour, TrashRetention())
+
+	test.Setenv("TRASH_RETENTION", "0")
+	assert.Zero(test, TrashRetention())
+}
+
+func TestTrashEntryOf(test *testing.T) {
+	test.Setenv("TRASH_RETENTION", "1h")
+
+	var (
+		deletedAt    = time.Now()
+		autoDeleteAt = deletedAt.Add(1 * time.Minute)
+	)
+
+	attrs := &storage.ObjectAttrs{
+		Name:        trashObject("test", "1700000000000000"),
+		ContentType: fiber.MIMETextPlainCharsetUTF8,
+		Metadata: map[string]string{
+			metadataDeletedAt:  fmt.Sprintf("%d", deletedAt.UnixMilli()),
+			metadataTrashName:  "ok.txt",
+			HeaderAutoDeleteAt: fmt.Sprintf("%d", deletedAt.Add(2*time.Hour).UnixMilli()),
+			MetadataBlob:       blobHash([]byte("is ok")),
+			MetadataBlobSize:   "5",
+		},
+	}
+
+	entry, err := trashEntryOf(attrs)
+	require.NoError(test, err)
+
+	assert.Equal(test, "1700000000000000", entry.Id)
+	assert.Equal(test, "ok.txt", entry.Name)
+	assert.Equal(test, int64(5), entry.Size)
+	assert.Equal(test, deletedAt.Add(1*time.Hour).UnixMilli(), entry.PurgeAt)
+
+	// never outlive auto delete of the file
+	attrs.Metadata[HeaderAutoDeleteAt] = fmt.Sprintf("%d", autoDeleteAt.UnixMilli())
+
+	entry, err = trashEntryOf(attrs)
+	require.NoError(test, err)
+	assert.Equal(test, autoDeleteAt.UnixMilli(), entry.PurgeAt)
+}
+
+func TestRestoreTrash(test *testing.T) {
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+	defer cancel()
+
+	_, err := RestoreTrash(storeCtx, "test", "../ok.txt")
+	require.ErrorIs(test, err, ErrorTrashNotFound)
+
+	require.ErrorIs(test, PurgeTrash(storeCtx, "test", "invalid"), ErrorTrashNotFound)
+}
+
+func TestTrash(test *testing.T) {
+	const (
+		username = "testtrash"
+		filePath = username + "/ok.txt"
+	)
+
+	test.Setenv("TRASH_RETENTION", "1h")
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+		utils.LogErr(SweepTrash(storeCtx, username, true))
+	})
+
+	fileData := func() *models.DataFile {
+		return &models.DataFile{
+			AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+			PrivateUrlExpires: 10,
+			MimeType:          fiber.MIMETextPlainCharsetUTF8,
+			MaxVersions:       1,
+		}
+	}
+
+	require.NoError(test, UploadObject(storeCtx, filePath, []byte("first"), fileData()))
+	require.NoError(test, ReplaceObject(storeCtx, filePath, []byte("second"), fileData()))
+
+	require.NoError(test, TrashObject(storeCtx, filePath))
+
+	_, err := GetObject(storeCtx, filePath)
+	require.ErrorIs(test, err, storage.ErrObjectNotExist)
+
+	entries, err := ListTrash(storeCtx, username)
+	require.NoError(test, err)
+	require.Len(test, entries, 1)
+	assert.Equal(test, "ok.txt", entries[0].Name)
+	assert.Equal(test, int64(len("second")), entries[0].Size)
+
+	restoredPath, err := RestoreTrash(storeCtx, username, entries[0].Id)
+	require.NoError(test, err)
+	assert.Equal(test, filePath, restoredPath)
+
+	// versions is restored together with the file
+	fileByte, err := ReadObject(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Equal(test, []byte("second"), fileByte)
+
+	versions, err := ListVersions(storeCtx, filePath)
+	require.NoError(test, err)
+	require.Len(test, versions, 1)
+
+	entries, err = ListTrash(storeCtx, username)
+	require.NoError(test, err)
+	assert.Empty(test, entries)
+}
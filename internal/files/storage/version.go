@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"golang.org/x/exp/slices"
+	"google.golang.org/api/iterator"
+)
+
+// Version is reference object `.tempsy/versions/<username>/<filename>/<generation>` to the blob of previous content,
+// generation of replaced object is used as id, so the versions are listed from the oldest
+const (
+	metadataVersionCreatedAt = "file-version-created-at"
+	versionPrefix            = "versions/"
+)
+
+var ErrorVersionNotFound = errors.New("version_not_found")
+
+func versionObject(filePath, id string) string {
+	return versionsPrefix(filePath) + id
+}
+
+func versionsPrefix(filePath string) string {
+	return RecordPrefix + versionPrefix + filePath + "/"
+}
+
+// validVersionId id is generation of object
+func validVersionId(id string) bool {
+	generation, err := strconv.ParseInt(id, 10, 64)
+	return err == nil && generation > 0 && fmt.Sprintf("%d", generation) == id
+}
+
+// keepVersion add version that reference the content of object before it's replaced,
+// object uploaded before deduplication is stored as blob first
+func keepVersion(ctx context.Context, bucket *storage.BucketHandle, filePath string, attrs *storage.ObjectAttrs) error {
+	fileData := &models.DataFile{FileId: attrs.Metadata[MetadataFileId]}
+	if err := UnmarshalMetadata(attrs.Metadata, fileData); err != nil {
+		return err
+	}
+
+	metadata, err := objectMetadata(fileData)
+	if err != nil {
+		return err
+	}
+
+	key := attrs.Metadata[MetadataBlob]
+	if key != "" {
+		// the blob is still referenced by the object, so it's never created here
+		err = acquireBlob(ctx, bucket, key, func(*storage.ObjectHandle, map[string]string) error {
+			return storage.ErrObjectNotExist
+		})
+		if err != nil {
+			return err
+		}
+
+		metadata[MetadataBlobSize] = attrs.Metadata[MetadataBlobSize]
+		metadata[MetadataCrc32c] = attrs.Metadata[MetadataCrc32c]
+	} else {
+		fileByte, err := ReadObject(ctx, filePath)
+		if err != nil {
+			return err
+		}
+
+		key = contentBlobKey(fileByte)
+		if err = acquireBlob(ctx, bucket, key, writeBlob(ctx, fileByte)); err != nil {
+			return err
+		}
+
+		metadata[MetadataBlobSize] = fmt.Sprintf("%d", len(fileByte))
+		metadata[MetadataCrc32c] = encodeCrc32c(contentCrc32c(fileByte))
+	}
+	metadata[MetadataBlob] = key
+	metadata[metadataVersionCreatedAt] = fmt.Sprintf("%d", attrs.Created.UnixMilli())
+
+	writer := bucket.Object(versionObject(filePath, fmt.Sprintf("%d", attrs.Generation))).If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	writer.ContentType = attrs.ContentType
+	writer.Metadata = metadata
+
+	if err = writer.Close(); err != nil {
+		return errors.Join(err, releaseBlob(ctx, bucket, key))
+	}
+
+	return nil
+}
+
+// pruneVersions delete the oldest versions, so only maxVersions is kept, -1 keep all versions
+func pruneVersions(ctx context.Context, bucket *storage.BucketHandle, filePath string, maxVersions int) error {
+	if maxVersions < 0 {
+		return nil
+	}
+
+	names, err := versionNames(ctx, bucket, filePath)
+	if err != nil {
+		return err
+	}
+
+	if len(names) <= maxVersions {
+		return nil
+	}
+
+	// the oldest versions
+	return Batch(ctx, names[:len(names)-maxVersions], func(ctx context.Context, _ int, name string) error {
+		return deleteVersion(ctx, bucket, name)
+	})
+}
+
+// versionNames return object name of versions from the oldest
+func versionNames(ctx context.Context, bucket *storage.BucketHandle, filePath string) ([]string, error) {
+	var (
+		objects = bucket.Objects(ctx, &storage.Query{Prefix: versionsPrefix(filePath)})
+		names   = make([]string, 0)
+	)
+
+	for {
+		obj, err := objects.Next()
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				break
+			}
+			return nil, err
+		}
+
+		names = append(names, obj.Name)
+	}
+
+	// generation is sorted as number, since listed object name is sorted as string
+	slices.SortFunc(names, func(a, b string) int {
+		generationA, _ := strconv.ParseInt(a[strings.LastIndex(a, "/")+1:], 10, 64)
+		generationB, _ := strconv.ParseInt(b[strings.LastIndex(b, "/")+1:], 10, 64)
+
+		switch {
+		case generationA < generationB:
+			return -1
+		case generationA > generationB:
+			return 1
+		}
+		return 0
+	})
+
+	return names, nil
+}
+
+func deleteVersion(ctx context.Context, bucket *storage.BucketHandle, name string) error {
+	obj := bucket.Object(name)
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = obj.If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx); err != nil {
+		return err
+	}
+
+	return releaseBlob(ctx, bucket, attrs.Metadata[MetadataBlob])
+}
+
+func versionOf(attrs *storage.ObjectAttrs) (*models.FileVersion, error) {
+	size, err := strconv.ParseInt(attrs.Metadata[MetadataBlobSize], 10, 64)
+	if err != nil {
+		return nil, errors.New("blob_size_must_be_valid_integer")
+	}
+
+	createdAt, err := strconv.ParseInt(attrs.Metadata[metadataVersionCreatedAt], 10, 64)
+	if err != nil {
+		return nil, errors.New("version_created_at_must_be_valid_integer")
+	}
+
+	return &models.FileVersion{
+		Id:        attrs.Name[strings.LastIndex(attrs.Name, "/")+1:],
+		MimeType:  attrs.ContentType,
+		Size:      size,
+		Sha256:    blobSha256(attrs.Metadata[MetadataBlob]),
+		E2eHeader: attrs.Metadata[HeaderE2eHeader],
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// ListVersions return versions of the file from the newest
+func ListVersions(ctx context.Context, filePath string) ([]*models.FileVersion, error) {
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	bucket := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET"))
+
+	names, err := versionNames(ctx, bucket, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]*models.FileVersion, len(names))
+	err = Batch(ctx, names, func(ctx context.Context, i int, name string) error {
+		attrs, err := bucket.Object(name).Attrs(ctx)
+		if err != nil {
+			// pruned while listing
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		found[i], err = versionOf(attrs)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*models.FileVersion, 0, len(names))
+	for i := len(found) - 1; i >= 0; i-- {
+		if found[i] != nil {
+			versions = append(versions, found[i])
+		}
+	}
+
+	return versions, nil
+}
+
+// ReadVersion return version and the content, ErrorVersionNotFound when version is not exists
+func ReadVersion(ctx context.Context, filePath, id string) (*models.FileVersion, []byte, error) {
+	if !validVersionId(id) {
+		return nil, nil, ErrorVersionNotFound
+	}
+
+	client, err := createClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		utils.LogErr(client.Close())
+	}()
+
+	attrs, err := client.Bucket(os.Getenv("GOOGLE_CLOUD_STORAGE_BUCKET")).Object(versionObject(filePath, id)).Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, nil, ErrorVersionNotFound
+		}
+		return nil, nil, err
+	}
+
+	version, err := versionOf(attrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileByte, err := ReadObject(ctx, attrs.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return version, fileByte, nil
+}
+
+// RestoreVersion replace content of the file with the version, settings of the file is kept.
+// The replaced content is kept as new version when versioning is still enabled
+func RestoreVersion(ctx context.Context, filePath, id string) error {
+	fileData, err := GetObject(ctx, filePath)
+	if err != nil {
+		return err
+	}
+
+	version, fileByte, err := ReadVersion(ctx, filePath, id)
+	if err != nil {
+		return err
+	}
+
+	// content specific metadata must follow the content
+	fileData.MimeType = version.MimeType
+	fileData.E2eHeader = version.E2eHeader
+
+	return ReplaceObject(ctx, filePath, fileByte, fileData)
+}
+
+// deleteVersions delete all versions of the file, when the file is deleted
+func deleteVersions(ctx context.Context, bucket *storage.BucketHandle, filePath string) error {
+	return pruneVersions(ctx, bucket, filePath, 0)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidVersionId(test *testing.T) {
+	assert.True(test, validVersionId("1700000000000000"))
+	assert.False(test, validVersionId(""))
+	assert.False(test, validVersionId("0"))
+	assert.False(test, validVersionId("01700000000000000"))
+	assert.False(test, validVersionId("../ok.txt"))
+	assert.Equal(test, ".tempsy/versions/test/ok.txt/1", versionObject("test/ok.txt", "1"))
+}
+
+func TestReadVersion(test *testing.T) {
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+	defer cancel()
+
+	_, _, err := ReadVersion(storeCtx, "test/ok.txt", "invalid")
+	require.ErrorIs(test, err, ErrorVersionNotFound)
+}
+
+func TestVersion(test *testing.T) {
+	const filePath = "testversion/ok.txt"
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(DeleteObject(storeCtx, filePath))
+	})
+
+	fileData := func() *models.DataFile {
+		return &models.DataFile{
+			AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+			PrivateUrlExpires: 10,
+			MimeType:          fiber.MIMETextPlainCharsetUTF8,
+			MaxVersions:       2,
+		}
+	}
+
+	require.NoError(test, UploadObject(storeCtx, filePath, []byte("first"), fileData()))
+	for _, content := range []string{"second", "third", "fourth"} {
+		require.NoError(test, ReplaceObject(storeCtx, filePath, []byte(content), fileData()))
+	}
+
+	// only the last 2 versions is kept, from the newest
+	versions, err := ListVersions(storeCtx, filePath)
+	require.NoError(test, err)
+	require.Len(test, versions, 2)
+	assert.Equal(test, blobHash([]byte("third")), versions[0].Sha256)
+	assert.Equal(test, blobHash([]byte("second")), versions[1].Sha256)
+
+	version, fileByte, err := ReadVersion(storeCtx, filePath, versions[1].Id)
+	require.NoError(test, err)
+	assert.Equal(test, []byte("second"), fileByte)
+	assert.Equal(test, int64(len("second")), version.Size)
+
+	require.NoError(test, RestoreVersion(storeCtx, filePath, versions[1].Id))
+
+	fileByte, err = ReadObject(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Equal(test, []byte("second"), fileByte)
+
+	// restored content is kept as new version
+	versions, err = ListVersions(storeCtx, filePath)
+	require.NoError(test, err)
+	require.Len(test, versions, 2)
+	assert.Equal(test, blobHash([]byte("fourth")), versions[0].Sha256)
+
+	require.NoError(test, DeleteObject(storeCtx, filePath))
+
+	versions, err = ListVersions(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Empty(test, versions)
+}
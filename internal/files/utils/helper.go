@@ -1,21 +1,53 @@
 package utils
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2/log"
 )
 
 const (
-	ErrorTypeFileNotPublic     = "file_not_found_or_not_public"
-	ErrorTypeFileNotFound      = "file_not_found"
-	ErrorTypeHaveToken         = "already_have_valid_token"
-	ErrorTypeInvalidToken      = "invalid_token"
-	ErrorTypeEmptyData         = "delete_empty_data"
-	ErrorTypeInvalidHeaderFile = "invalid_header_file"
-	ErrorTypeEmptyFile         = "invalid_empty_file"
-	ErrorTypeMismatchType      = "mismatch_content_type"
-	ErrorTypeFileExists        = "file_already_exists"
-	ErrorTypeInvalidFileName   = "invalid_file_name"
-	ErrorTypeUnsupportedType   = "unsupported_content_type"
+	ErrorTypeFileNotPublic      = "file_not_found_or_not_public"
+	ErrorTypeFileNotFound       = "file_not_found"
+	ErrorTypeHaveToken          = "already_have_valid_token"
+	ErrorTypeInvalidToken       = "invalid_token"
+	ErrorTypeAuthUnavailable    = "auth_service_unavailable"
+	ErrorTypeEmptyData          = "delete_empty_data"
+	ErrorTypeInvalidHeaderFile  = "invalid_header_file"
+	ErrorTypeEmptyFile          = "invalid_empty_file"
+	ErrorTypeMismatchType       = "mismatch_content_type"
+	ErrorTypeFileExists         = "file_already_exists"
+	ErrorTypeInvalidFileName    = "invalid_file_name"
+	ErrorTypeUnsupportedType    = "unsupported_content_type"
+	ErrorTypeContentMismatch    = "content_type_mismatch"
+	ErrorTypePasswordRequired   = "file_password_required"
+	ErrorTypeInvalidPassword    = "invalid_file_password"
+	ErrorTypeShareNotFound      = "share_not_found_or_expired"
+	ErrorTypeInvalidHeaderShare = "invalid_header_share"
+	ErrorTypeInvalidSignature   = "invalid_or_expired_signature"
+	ErrorTypeUploadNotFound     = "upload_not_found_or_expired"
+	ErrorTypeUploadIncomplete   = "upload_not_completed"
+	ErrorTypeInvalidFileSize    = "invalid_file_size"
+	ErrorTypeChecksumMismatch   = "checksum_mismatch"
+	ErrorTypeVersionNotFound    = "version_not_found"
+	ErrorTypeTrashNotFound      = "trash_not_found"
+	ErrorTypeInvalidFilter      = "invalid_filter_query"
+	ErrorTypeInvalidBody        = "invalid_request_body"
+	ErrorTypeInvalidArchive     = "invalid_archive_format"
+	ErrorTypeArchiveTooLarge    = "archive_limit_exceeded"
+	ErrorTypeGrantNotFound      = "grant_not_found"
+	ErrorTypeInvalidHeaderGrant = "invalid_header_grant"
+	ErrorTypeInvalidTransfer    = "invalid_transfer_owner"
+	ErrorTypeInvalidOrg         = "invalid_org"
+	ErrorTypeOrgExists          = "org_already_exists"
+	ErrorTypeOrgNotFound        = "org_not_found"
+	ErrorTypeOrgNotEmpty        = "org_not_empty"
+	ErrorTypeOrgMemberNotFound  = "org_member_not_found"
+	ErrorTypeOrgOwnerRequired   = "org_owner_required"
+	ErrorTypeQuotaExceeded      = "org_quota_exceeded"
+	ErrorTypeInvalidApiKey      = "invalid_api_key"
+	ErrorTypeApiKeyNotFound     = "api_key_not_found"
+	ErrorTypeTooManyApiKeys     = "too_many_api_keys"
 )
 
 // Check is a helper function to check error and panic if error is not nil
@@ -31,3 +63,8 @@ func LogErr(err error) {
 		log.Error(err)
 	}
 }
+
+// Describe turn snake case error or error type into readable description of api error
+func Describe(errType string) string {
+	return strings.ReplaceAll(errType, "_", " ")
+}
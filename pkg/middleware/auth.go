@@ -1,19 +1,26 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 
+	"cloud.google.com/go/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/auth"
-	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
-	"github.com/afifurrohman-id/tempsy/internal/files/auth/oauth2"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"golang.org/x/exp/slices"
 )
 
+// getPendingUpload is replaced in test, so presigned upload can be checked without storage
+var getPendingUpload = store.GetPendingUpload
+
 func CheckHttpMethod(ctx *fiber.Ctx) error {
 	method := ctx.Method()
 	if !slices.Contains(auth.AllowedHttpMethod, method) {
@@ -28,30 +35,310 @@ func CheckHttpMethod(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
+// CheckAuth allow only the owner of username, or member of organization when username is organization namespace
+// (reader can only read, other methods require writer)
 func CheckAuth(ctx *fiber.Ctx) error {
-	var (
-		username  = ctx.Params("username")
-		authToken = ctx.Get(fiber.HeaderAuthorization)
-	)
+	user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+	if err != nil {
+		return identifyFailed(ctx, err)
+	}
 
-	if strings.HasPrefix(authToken, auth.BearerPrefix) {
-		if strings.HasPrefix(username, guest.UsernamePrefix) {
-			tokenMap, err := guest.ParseToken(strings.TrimPrefix(authToken, auth.BearerPrefix))
+	ctx.Locals(identity.LocalKey, user)
 
-			if err == nil && tokenMap["jti"] == username {
-				return ctx.Next()
+	if !allowApiKey(ctx, user) {
+		return forbiddenApiKey(ctx)
+	}
+
+	username := ctx.Params("username")
+	if orgName, ok := store.OrgName(username); ok {
+		role := store.OrgRoleWriter
+		if ctx.Method() == fiber.MethodGet || ctx.Method() == fiber.MethodHead {
+			role = store.OrgRoleReader
+		}
+
+		return checkOrgRole(ctx, user, orgName, role)
+	}
+
+	if user.UserName != username {
+		return unauthorized(ctx)
+	}
+
+	return ctx.Next()
+}
+
+// Authenticate allow any user with valid token, e.g. to create organization or api key.
+// Api key only has access to files, so it cannot be used to manage account
+func Authenticate(ctx *fiber.Ctx) error {
+	user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+	if err != nil {
+		return identifyFailed(ctx, err)
+	}
+
+	if user.ApiKey != nil {
+		return forbiddenApiKey(ctx)
+	}
+
+	ctx.Locals(identity.LocalKey, user)
+	return ctx.Next()
+}
+
+// CheckOrgRole allow member of organization in path params `org` with at least the role
+func CheckOrgRole(role string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+		if err != nil {
+			return identifyFailed(ctx, err)
+		}
+
+		if user.ApiKey != nil {
+			return forbiddenApiKey(ctx)
+		}
+
+		ctx.Locals(identity.LocalKey, user)
+		return checkOrgRole(ctx, user, ctx.Params("org"), role)
+	}
+}
+
+// checkOrgRole load organization into locals when user is member with at least the role,
+// organization that is not exist is the same as not a member, so its name is not leaked
+func checkOrgRole(ctx *fiber.Ctx, user *identity.Identity, orgName, role string) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	org, err := store.GetOrg(storeCtx, orgName)
+	if err != nil {
+		if errors.Is(err, store.ErrorOrgNotFound) {
+			return unauthorized(ctx)
+		}
+		log.Panic(err)
+	}
+
+	memberRole, ok := org.Members[user.UserName]
+	if !ok {
+		return unauthorized(ctx)
+	}
+
+	if !store.OrgRoleAllows(memberRole, role) {
+		return forbidden(ctx, memberRole)
+	}
+
+	ctx.Locals(store.LocalOrg, org)
+	return ctx.Next()
+}
+
+// CheckFileAccess allow the owner of file, or other user that is granted access to the file with at least the role
+func CheckFileAccess(role string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+		if err != nil {
+			return identifyFailed(ctx, err)
+		}
+
+		ctx.Locals(identity.LocalKey, user)
+
+		if !allowApiKey(ctx, user) {
+			return forbiddenApiKey(ctx)
+		}
+
+		username := ctx.Params("username")
+		if user.UserName == username {
+			return ctx.Next()
+		}
+
+		storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+		defer cancel()
+
+		// member of organization use its role, other user can still have access by grant
+		if orgName, ok := store.OrgName(username); ok {
+			org, err := store.GetOrg(storeCtx, orgName)
+			if err != nil && !errors.Is(err, store.ErrorOrgNotFound) {
+				log.Panic(err)
 			}
-			utils.LogErr(err)
-		} else {
-			accountInfo, err := oauth2.GetGoogleAccountInfo(strings.TrimPrefix(authToken, auth.BearerPrefix))
 
-			if err == nil && username == accountInfo.UserName && accountInfo.VerifiedEmail {
+			memberRole := ""
+			if org != nil {
+				memberRole = org.Members[user.UserName]
+			}
+
+			if memberRole != "" {
+				orgRole := store.OrgRoleReader
+				if role == store.GrantRoleWrite {
+					orgRole = store.OrgRoleWriter
+				}
+
+				if !store.OrgRoleAllows(memberRole, orgRole) {
+					return forbidden(ctx, memberRole)
+				}
+
+				ctx.Locals(store.LocalOrg, org)
 				return ctx.Next()
 			}
-			utils.LogErr(err)
 		}
+
+		filePath := fmt.Sprintf("%s/%s", username, store.DecodeFileName(ctx.Params("filename")))
+
+		record, err := store.GetGrant(storeCtx, filePath, user.UserName)
+		if err != nil {
+			if errors.Is(err, store.ErrorGrantNotFound) {
+				return unauthorized(ctx)
+			}
+			log.Panic(err)
+		}
+
+		// grant of previous file with the same name
+		fileData, err := store.GetObject(storeCtx, filePath)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+			log.Panic(err)
+		}
+		if err != nil || fileData.FileId != record.FileId {
+			return unauthorized(ctx)
+		}
+
+		if !record.Allows(role) {
+			return forbidden(ctx, record.Role)
+		}
+
+		ctx.Locals(store.LocalGrant, record)
+		return ctx.Next()
+	}
+}
+
+// allowApiKey check request is within scopes and path prefix of api key, user that is not authenticated by api key is always allowed
+func allowApiKey(ctx *fiber.Ctx, user *identity.Identity) bool {
+	if user.ApiKey == nil {
+		return true
+	}
+
+	for _, scope := range apiKeyScopes(ctx) {
+		if !user.ApiKey.HasScope(scope) {
+			return false
+		}
+	}
+
+	if user.ApiKey.PathPrefix == "" {
+		return true
+	}
+
+	// key with path prefix cannot access route that is not for specific file, e.g. list all files
+	fileNames := apiKeyFileNames(ctx)
+	if len(fileNames) == 0 {
+		return false
+	}
+
+	for _, fileName := range fileNames {
+		if !user.ApiKey.AllowsFileName(fileName) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apiKeyScopes return scopes that is required by the route
+func apiKeyScopes(ctx *fiber.Ctx) []string {
+	route := ctx.Route().Path
+
+	switch {
+	case ctx.Method() == fiber.MethodGet || ctx.Method() == fiber.MethodHead:
+		return []string{store.ApiKeyScopeRead}
+	// change who can access the file, the same as make it public
+	case strings.Contains(route, "/shares") || strings.Contains(route, "/grants"):
+		return []string{store.ApiKeyScopePublic}
+	case ctx.Method() == fiber.MethodDelete || strings.HasSuffix(route, "/transfer"):
+		return []string{store.ApiKeyScopeDelete}
+	}
+
+	scopes := []string{store.ApiKeyScopeUpload}
+	if strings.HasSuffix(route, "/move") {
+		scopes = append(scopes, store.ApiKeyScopeDelete)
+	}
+
+	if ctx.Get(store.HeaderIsPublic) != "" {
+		scopes = append(scopes, store.ApiKeyScopePublic)
+	}
+
+	return scopes
+}
+
+// apiKeyFileNames return all file names that is accessed or created by the request
+func apiKeyFileNames(ctx *fiber.Ctx) []string {
+	var fileNames []string
+	if fileName := ctx.Params("filename"); fileName != "" {
+		fileNames = append(fileNames, store.DecodeFileName(fileName))
 	}
 
+	// presigned upload is finalized to the file name that is stored when it's created
+	if ctx.Method() == fiber.MethodPost && strings.HasSuffix(ctx.Route().Path, "/presign/:id") {
+		return append(fileNames, pendingUploadFileName(ctx)...)
+	}
+
+	// name of new file, file of other route with id (e.g. trash) is only known after it's loaded
+	if ctx.Method() != fiber.MethodPost || ctx.Params("id") != "" {
+		return fileNames
+	}
+
+	if fileName := ctx.Get(store.HeaderFileName); fileName != "" {
+		fileNames = append(fileNames, store.DecodeFileName(fileName))
+	}
+
+	// extracted file name is prefixed with folder
+	if ctx.QueryBool("extract") {
+		fileNames = append(fileNames, ctx.Query("folder")+store.ExtractSeparator)
+	}
+
+	return fileNames
+}
+
+// pendingUploadFileName return file name of presigned upload in the namespace of request, nothing when it's not found
+func pendingUploadFileName(ctx *fiber.Ctx) []string {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	upload, err := getPendingUpload(storeCtx, ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrorUploadNotFound) {
+			return nil
+		}
+		log.Panic(err)
+	}
+
+	fileName, ok := strings.CutPrefix(upload.FilePath, ctx.Params("username")+"/")
+	if !ok {
+		return nil
+	}
+
+	return []string{fileName}
+}
+
+func forbiddenApiKey(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{Error: &models.Error{
+		Kind:        "forbidden",
+		Description: "Api key is not allowed to access this resources, check its scopes and path prefix",
+	}})
+}
+
+func forbidden(ctx *fiber.Ctx, role string) error {
+	return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{Error: &models.Error{
+		Kind:        "forbidden",
+		Description: fmt.Sprintf("You only have %s access to this resources", role),
+	}})
+}
+
+// identifyFailed response 503 when token cannot be verified because storage is unavailable, so client can retry with the same token
+func identifyFailed(ctx *fiber.Ctx, err error) error {
+	utils.LogErr(err)
+
+	if errors.Is(err, identity.ErrorUnavailable) {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(&models.ApiError{Error: &models.Error{
+			Kind:        utils.ErrorTypeAuthUnavailable,
+			Description: "Cannot verify token at the moment, please try again",
+		}})
+	}
+
+	return unauthorized(ctx)
+}
+
+func unauthorized(ctx *fiber.Ctx) error {
 	return ctx.Status(fiber.StatusUnauthorized).JSON(&models.ApiError{Error: &models.Error{
 		Kind:        "unauthorized",
 		Description: "You don't have right access to this resources",
@@ -59,6 +346,7 @@ func CheckAuth(ctx *fiber.Ctx) error {
 }
 
 var Cors = cors.New(cors.Config{
-	AllowMethods: strings.Join(auth.AllowedHttpMethod, ","),
-	AllowHeaders: strings.Join([]string{fiber.HeaderContentType, fiber.HeaderContentLength, fiber.HeaderAccept, fiber.HeaderUserAgent, fiber.HeaderAcceptEncoding, fiber.HeaderAcceptCharset, fiber.HeaderAuthorization, fiber.HeaderOrigin, fiber.HeaderLocation, fiber.HeaderKeepAlive}, ","),
+	ExposeHeaders: strings.Join([]string{store.HeaderE2eHeader, store.HeaderDigest, store.HeaderReprDigest}, ","),
+	AllowMethods:  strings.Join(auth.AllowedHttpMethod, ","),
+	AllowHeaders:  strings.Join([]string{fiber.HeaderContentType, fiber.HeaderContentLength, fiber.HeaderAccept, fiber.HeaderUserAgent, fiber.HeaderAcceptEncoding, fiber.HeaderAcceptCharset, fiber.HeaderAuthorization, auth.HeaderTransferAuthorization, auth.HeaderGuestAuthorization, fiber.HeaderOrigin, fiber.HeaderLocation, fiber.HeaderKeepAlive}, ","),
 })
@@ -1,18 +1,24 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/afifurrohman-id/tempsy/internal/files/auth"
 	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
 	"github.com/afifurrohman-id/tempsy/internal/files/auth/oauth2"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -126,3 +132,231 @@ func TestCheckAuth(test *testing.T) {
 		})
 	})
 }
+
+func TestCheckFileAccess(test *testing.T) {
+	app := fiber.New()
+
+	app.Get("/:username/:filename", CheckFileAccess(store.GrantRoleRead), func(ctx *fiber.Ctx) error {
+		return nil
+	})
+
+	test.Run("TestOkOwner", func(test *testing.T) {
+		username := guest.GenerateUsername()
+
+		token, err := guest.CreateToken(username)
+		require.NoError(test, err)
+
+		req := httptest.NewRequest(fiber.MethodGet, fmt.Sprintf("/%s/ok.txt", username), nil)
+		req.Header.Set(fiber.HeaderAuthorization, auth.BearerPrefix+token)
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		require.Equal(test, fiber.StatusOK, res.StatusCode)
+	})
+
+	test.Run("TestUnauthorized", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/test/ok.txt", nil)
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		require.Equal(test, fiber.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func TestAllowApiKey(test *testing.T) {
+	app := fiber.New()
+
+	allow := func(ctx *fiber.Ctx) error {
+		user := &identity.Identity{
+			UserName: "test",
+			ApiKey: &store.ApiKeyRecord{
+				Scopes:     strings.Split(ctx.Get("test-scopes"), ","),
+				PathPrefix: ctx.Get("test-path-prefix"),
+			},
+		}
+
+		if !allowApiKey(ctx, user) {
+			return forbiddenApiKey(ctx)
+		}
+		return nil
+	}
+
+	app.Get("/:username", allow)
+	app.Get("/:username/:filename", allow)
+	app.Post("/:username", allow)
+	app.Post("/:username/:filename/move", allow)
+	app.Post("/:username/:filename/shares", allow)
+	app.Delete("/:username/:filename", allow)
+	app.Post("/:username/presign/:id", allow)
+
+	test.Cleanup(func() {
+		getPendingUpload = store.GetPendingUpload
+	})
+
+	getPendingUpload = func(_ context.Context, id string) (*store.PendingUpload, error) {
+		switch id {
+		case "inside":
+			return &store.PendingUpload{Id: id, FilePath: "test/reports_ok.txt"}, nil
+		case "outside":
+			return &store.PendingUpload{Id: id, FilePath: "test/ok.txt"}, nil
+		}
+		return nil, store.ErrorUploadNotFound
+	}
+
+	tableTests := []struct {
+		name       string
+		method     string
+		url        string
+		scopes     string
+		pathPrefix string
+		headers    map[string]string
+		status     int
+	}{
+		{
+			name:   "TestOkRead",
+			method: fiber.MethodGet,
+			url:    "/test/ok.txt",
+			scopes: store.ApiKeyScopeRead,
+			status: fiber.StatusOK,
+		},
+		{
+			name:   "TestOnReadWithoutScope",
+			method: fiber.MethodGet,
+			url:    "/test/ok.txt",
+			scopes: store.ApiKeyScopeUpload,
+			status: fiber.StatusForbidden,
+		},
+		{
+			name:    "TestOkUpload",
+			method:  fiber.MethodPost,
+			url:     "/test",
+			scopes:  store.ApiKeyScopeUpload,
+			headers: map[string]string{store.HeaderFileName: "ok.txt"},
+			status:  fiber.StatusOK,
+		},
+		{
+			name:    "TestOnUploadPublicWithoutScope",
+			method:  fiber.MethodPost,
+			url:     "/test",
+			scopes:  store.ApiKeyScopeUpload,
+			headers: map[string]string{store.HeaderFileName: "ok.txt", store.HeaderIsPublic: "true"},
+			status:  fiber.StatusForbidden,
+		},
+		{
+			name:   "TestOnMoveWithoutDelete",
+			method: fiber.MethodPost,
+			url:    "/test/ok.txt/move",
+			scopes: store.ApiKeyScopeUpload,
+			status: fiber.StatusForbidden,
+		},
+		{
+			name:   "TestOnShareWithoutPublic",
+			method: fiber.MethodPost,
+			url:    "/test/ok.txt/shares",
+			scopes: store.ApiKeyScopeUpload,
+			status: fiber.StatusForbidden,
+		},
+		{
+			name:   "TestOkDelete",
+			method: fiber.MethodDelete,
+			url:    "/test/ok.txt",
+			scopes: store.ApiKeyScopeDelete,
+			status: fiber.StatusOK,
+		},
+		{
+			name:       "TestOkPathPrefix",
+			method:     fiber.MethodGet,
+			url:        "/test/reports_ok.txt",
+			scopes:     store.ApiKeyScopeRead,
+			pathPrefix: "reports_",
+			status:     fiber.StatusOK,
+		},
+		{
+			name:       "TestOnOutsidePathPrefix",
+			method:     fiber.MethodGet,
+			url:        "/test/ok.txt",
+			scopes:     store.ApiKeyScopeRead,
+			pathPrefix: "reports_",
+			status:     fiber.StatusForbidden,
+		},
+		{
+			name:       "TestOnListWithPathPrefix",
+			method:     fiber.MethodGet,
+			url:        "/test",
+			scopes:     store.ApiKeyScopeRead,
+			pathPrefix: "reports_",
+			headers:    map[string]string{store.HeaderFileName: "reports_ok.txt"},
+			status:     fiber.StatusForbidden,
+		},
+		{
+			name:       "TestOkExtractPathPrefix",
+			method:     fiber.MethodPost,
+			url:        "/test?extract=true&folder=reports",
+			scopes:     store.ApiKeyScopeUpload,
+			pathPrefix: "reports_",
+			headers:    map[string]string{store.HeaderFileName: "reports_ok.zip"},
+			status:     fiber.StatusOK,
+		},
+		{
+			name:       "TestOnExtractOutsidePathPrefix",
+			method:     fiber.MethodPost,
+			url:        "/test?extract=true",
+			scopes:     store.ApiKeyScopeUpload,
+			pathPrefix: "reports_",
+			headers:    map[string]string{store.HeaderFileName: "reports_ok.zip"},
+			status:     fiber.StatusForbidden,
+		},
+		{
+			name:       "TestOkFinalizePathPrefix",
+			method:     fiber.MethodPost,
+			url:        "/test/presign/inside",
+			scopes:     store.ApiKeyScopeUpload,
+			pathPrefix: "reports_",
+			status:     fiber.StatusOK,
+		},
+		{
+			name:       "TestOnFinalizeOutsidePathPrefix",
+			method:     fiber.MethodPost,
+			url:        "/test/presign/outside",
+			scopes:     store.ApiKeyScopeUpload,
+			pathPrefix: "reports_",
+			status:     fiber.StatusForbidden,
+		},
+		{
+			name:       "TestOnFinalizeNotFound",
+			method:     fiber.MethodPost,
+			url:        "/test/presign/not-found",
+			scopes:     store.ApiKeyScopeUpload,
+			pathPrefix: "reports_",
+			status:     fiber.StatusForbidden,
+		},
+	}
+
+	for _, tableTest := range tableTests {
+		test.Run(tableTest.name, func(test *testing.T) {
+			req := httptest.NewRequest(tableTest.method, tableTest.url, nil)
+			req.Header.Set("test-scopes", tableTest.scopes)
+			req.Header.Set("test-path-prefix", tableTest.pathPrefix)
+			for key, value := range tableTest.headers {
+				req.Header.Set(key, value)
+			}
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			assert.Equal(test, tableTest.status, res.StatusCode)
+		})
+	}
+}
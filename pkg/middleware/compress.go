@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+var compressor = fasthttp.CompressHandlerBrotliLevel(func(*fasthttp.RequestCtx) {}, fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression)
+
+// Compress compress response with brotli or gzip, the same as compress middleware of fiber.
+// File content with digest header is not compressed, since the digest is of the content that is served
+func Compress(ctx *fiber.Ctx) error {
+	if err := ctx.Next(); err != nil {
+		return err
+	}
+
+	if ctx.GetRespHeader(store.HeaderReprDigest) != "" {
+		return nil
+	}
+
+	compressor(ctx.Context())
+	return nil
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(test *testing.T) {
+	var (
+		app      = fiber.New()
+		fileByte = []byte(strings.Repeat("is ok ", 1000))
+	)
+
+	app.Use(Compress)
+	app.Get("/file", func(ctx *fiber.Ctx) error {
+		store.SetDigestHeaders(ctx, fileByte)
+		ctx.Type("txt")
+		return ctx.Send(fileByte)
+	})
+	app.Get("/json", func(ctx *fiber.Ctx) error {
+		ctx.Type("txt")
+		return ctx.Send(fileByte)
+	})
+
+	for path, encoding := range map[string]string{"/file": "", "/json": "gzip"} {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		req.Header.Set(fiber.HeaderAcceptEncoding, "gzip")
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, encoding, res.Header.Get(fiber.HeaderContentEncoding), path)
+	}
+}
@@ -2,26 +2,41 @@ package middleware
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/afifurrohman-id/tempsy/internal/files/auth"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/utils"
 )
 
 const (
-	MaxBodyLimit               = 30 << 20 // 30MB
-	MaxReqProcsPerSeconds      = 30
-	MaxReqGuestTokenPerSeconds = 3
+	MaxBodyLimit                   = 30 << 20 // 30MB
+	MaxReqProcsPerSeconds          = 30
+	MaxReqGuestTokenPerSeconds     = 3
+	MaxPublicArchivePerMinute      = 10
+	MaxFilePasswordAttempts        = 5
+	MaxFilePasswordAttemptsPerFile = 50 // wrong attempts of all clients, against brute-force from many addresses
+	FilePasswordAttemptsDuration   = 5 * time.Minute
 )
 
 var RateLimiterProcessing = limiter.New(limiter.Config{
 	KeyGenerator: func(ctx *fiber.Ctx) string {
+		authorization := ctx.Get(fiber.HeaderAuthorization)
+
+		// api key is limited by its id, so request with wrong secret is counted to the key
+		if id, ok := store.ApiKeyId(strings.TrimPrefix(authorization, auth.BearerPrefix)); ok {
+			return store.ApiKeyPrefix + id
+		}
+
 		/* Go fiber is immutable by default,
 		need to copy the string to prevent unexpected behavior
 		*/
-		return utils.CopyString(ctx.Get(fiber.HeaderAuthorization))
+		return utils.CopyString(authorization)
 	},
 	Max: MaxReqProcsPerSeconds,
 	LimitReached: func(ctx *fiber.Ctx) error {
@@ -34,25 +49,93 @@ var RateLimiterProcessing = limiter.New(limiter.Config{
 	},
 })
 
-var RateLimiterGuestToken = limiter.New(limiter.Config{
-	Max: MaxReqGuestTokenPerSeconds,
-	KeyGenerator: func(ctx *fiber.Ctx) string {
-		var (
-			realIp  = ctx.Get(auth.HeaderRealIp)
-			xRealIp = ctx.Get(auth.HeaderXRealIp)
-		)
+// RateLimiterFilePassword limit wrong password attempt per client of file or share link, to prevent brute-force.
+// Only response of wrong password is counted (marked by store.LocalInvalidPassword), so visitor that is asked for password
+// or file that is not found never lock the file. Attempt of all clients is also limited by higher limit per file
+func RateLimiterFilePassword(ctx *fiber.Ctx) error {
+	var (
+		fileKey   = filePasswordKey(ctx)
+		clientKey = clientIp(ctx) + "|" + fileKey
+	)
 
-		if realIp != "" {
-			return utils.CopyString(realIp)
-		}
+	if passwordAttempts.reached(clientKey, MaxFilePasswordAttempts) || passwordAttempts.reached(fileKey, MaxFilePasswordAttemptsPerFile) {
+		return ctx.Status(fiber.StatusTooManyRequests).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        "too_many_password_attempts",
+				Description: fmt.Sprintf("Maximum Password Attempts Exceeded, Maximum %d Wrong Attempts per %s for file", MaxFilePasswordAttempts, FilePasswordAttemptsDuration),
+			},
+		})
+	}
+
+	err := ctx.Next()
+
+	if invalid, _ := ctx.Locals(store.LocalInvalidPassword).(bool); invalid {
+		passwordAttempts.add(clientKey)
+		passwordAttempts.add(fileKey)
+	}
+
+	return err
+}
+
+// filePasswordKey return share id or decoded file path, so percent encoded or not normalized name of the same file
+// share the same limit
+func filePasswordKey(ctx *fiber.Ctx) string {
+	if id := ctx.Params("id"); id != "" {
+		return "share:" + id
+	}
+
+	return ctx.Params("username") + "/" + store.DecodeFileName(ctx.Params("filename"))
+}
+
+// attemptLimiter count attempts by key in memory, count is reset after FilePasswordAttemptsDuration since the first attempt
+type attemptLimiter struct {
+	mu        sync.Mutex
+	attempts  map[string]*attemptEntry
+	lastSweep time.Time
+}
+
+type attemptEntry struct {
+	count   int
+	resetAt time.Time
+}
+
+var passwordAttempts = &attemptLimiter{attempts: make(map[string]*attemptEntry)}
+
+func (limit *attemptLimiter) reached(key string, max int) bool {
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
 
-		if xRealIp != "" {
-			return utils.CopyString(xRealIp)
+	entry, ok := limit.attempts[key]
+	return ok && time.Now().Before(entry.resetAt) && entry.count >= max
+}
+
+func (limit *attemptLimiter) add(key string) {
+	limit.mu.Lock()
+	defer limit.mu.Unlock()
+
+	now := time.Now()
+
+	// expired attempts is removed at most once per duration
+	if now.Sub(limit.lastSweep) >= FilePasswordAttemptsDuration {
+		for attemptKey, entry := range limit.attempts {
+			if !now.Before(entry.resetAt) {
+				delete(limit.attempts, attemptKey)
+			}
 		}
+		limit.lastSweep = now
+	}
 
-		// ctx.IP() is copy by default
-		return ctx.IP()
-	},
+	entry, ok := limit.attempts[key]
+	if !ok || !now.Before(entry.resetAt) {
+		entry = &attemptEntry{resetAt: now.Add(FilePasswordAttemptsDuration)}
+		limit.attempts[key] = entry
+	}
+	entry.count++
+}
+
+var RateLimiterGuestToken = limiter.New(limiter.Config{
+	Max:          MaxReqGuestTokenPerSeconds,
+	KeyGenerator: clientIp,
 	LimitReached: func(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusTooManyRequests).JSON(&models.ApiError{
 			Error: &models.Error{
@@ -62,3 +145,36 @@ var RateLimiterGuestToken = limiter.New(limiter.Config{
 		})
 	},
 })
+
+// RateLimiterPublicArchive limit public archive per client, since it's not authenticated and expensive to build
+var RateLimiterPublicArchive = limiter.New(limiter.Config{
+	Max:          MaxPublicArchivePerMinute,
+	Expiration:   1 * time.Minute,
+	KeyGenerator: clientIp,
+	LimitReached: func(ctx *fiber.Ctx) error {
+		return ctx.Status(fiber.StatusTooManyRequests).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        "too_many_request",
+				Description: fmt.Sprintf("Maximum Request Exceeded, Maximum %d Request per minute for public archive", MaxPublicArchivePerMinute),
+			},
+		})
+	},
+})
+
+func clientIp(ctx *fiber.Ctx) string {
+	var (
+		realIp  = ctx.Get(auth.HeaderRealIp)
+		xRealIp = ctx.Get(auth.HeaderXRealIp)
+	)
+
+	if realIp != "" {
+		return utils.CopyString(realIp)
+	}
+
+	if xRealIp != "" {
+		return utils.CopyString(xRealIp)
+	}
+
+	// ctx.IP() is copy by default
+	return ctx.IP()
+}
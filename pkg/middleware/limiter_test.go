@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/afifurrohman-id/tempsy/internal/files/auth"
 	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -121,3 +123,86 @@ func TestLimitGuestToken(test *testing.T) {
 		}
 	})
 }
+
+func TestLimitFilePassword(test *testing.T) {
+	app := fiber.New()
+	app.Get("/:username/:filename", RateLimiterFilePassword, func(ctx *fiber.Ctx) error {
+		switch ctx.Get("file-password") {
+		case "secret":
+			return nil
+		case "":
+			return ctx.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		ctx.Locals(store.LocalInvalidPassword, true)
+		return ctx.SendStatus(fiber.StatusUnauthorized)
+	})
+
+	attempt := func(test *testing.T, path, password, ip string) int {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if password != "" {
+			req.Header.Set("file-password", password)
+		}
+		if ip != "" {
+			req.Header.Set(auth.HeaderXRealIp, ip)
+		}
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		return res.StatusCode
+	}
+
+	test.Run("TestOkSuccessNotCounted", func(test *testing.T) {
+		for i := 0; i <= MaxFilePasswordAttempts; i++ {
+			assert.Equal(test, fiber.StatusOK, attempt(test, "/test/ok.txt", "secret", ""))
+		}
+	})
+
+	test.Run("TestOkPasswordRequiredNotCounted", func(test *testing.T) {
+		for i := 0; i <= MaxFilePasswordAttempts; i++ {
+			assert.Equal(test, fiber.StatusUnauthorized, attempt(test, "/test/required.txt", "", ""))
+		}
+
+		assert.Equal(test, fiber.StatusOK, attempt(test, "/test/required.txt", "secret", ""))
+	})
+
+	test.Run("TestLimit", func(test *testing.T) {
+		for i := 0; i <= MaxFilePasswordAttempts; i++ {
+			if i < MaxFilePasswordAttempts {
+				assert.Equal(test, fiber.StatusUnauthorized, attempt(test, "/test/secret.txt", "wrong", ""))
+			} else {
+				assert.Equal(test, fiber.StatusTooManyRequests, attempt(test, "/test/secret.txt", "wrong", ""))
+			}
+		}
+
+		// other file and other client is not affected
+		assert.Equal(test, fiber.StatusUnauthorized, attempt(test, "/test/other.txt", "wrong", ""))
+		assert.Equal(test, fiber.StatusOK, attempt(test, "/test/secret.txt", "secret", "1.1.1.1"))
+	})
+
+	test.Run("TestLimitEncodedName", func(test *testing.T) {
+		// the same name, percent encoded and in NFC or NFD form
+		paths := []string{"/test/caf%C3%A9.txt", "/test/cafe%CC%81.txt", "/test/caf\u00e9.txt"}
+
+		for i := 0; i <= MaxFilePasswordAttempts; i++ {
+			if i < MaxFilePasswordAttempts {
+				assert.Equal(test, fiber.StatusUnauthorized, attempt(test, paths[i%len(paths)], "wrong", ""))
+			} else {
+				assert.Equal(test, fiber.StatusTooManyRequests, attempt(test, paths[i%len(paths)], "wrong", ""))
+			}
+		}
+	})
+
+	test.Run("TestLimitPerFile", func(test *testing.T) {
+		for i := 0; i < MaxFilePasswordAttemptsPerFile; i++ {
+			assert.Equal(test, fiber.StatusUnauthorized, attempt(test, "/test/target.txt", "wrong", fmt.Sprintf("10.0.%d.%d", i/256, i%256)))
+		}
+
+		assert.Equal(test, fiber.StatusTooManyRequests, attempt(test, "/test/target.txt", "secret", "8.8.8.8"))
+	})
+}
@@ -4,12 +4,10 @@ import (
 	"context"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	"golang.org/x/sync/errgroup"
-
 	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
 	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
@@ -17,6 +15,33 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cache"
 )
 
+// CheckFilesPath reject username that start with dot, so internal record under store.RecordPrefix
+// is never resolved as user files, and path segment with percent encoded path separator (e.g. `trash%2Falice`),
+// since path params is decoded by handler. It must be run before other middleware of the group
+func CheckFilesPath(ctx *fiber.Ctx) error {
+	if strings.HasPrefix(store.DecodeFileName(ctx.Params("username")), ".") {
+		return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeFileNotFound,
+				Description: "User files is not found",
+			},
+		})
+	}
+
+	for _, segment := range strings.Split(ctx.Path(), "/") {
+		if strings.ContainsAny(store.DecodeFileName(segment), "/\\") {
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeInvalidFileName,
+					Description: "Path cannot contain encoded path separator",
+				},
+			})
+		}
+	}
+
+	return ctx.Next()
+}
+
 func PurgeAnonymousAccount(ctx *fiber.Ctx) error {
 	username := ctx.Params("username")
 
@@ -35,24 +60,13 @@ func PurgeAnonymousAccount(ctx *fiber.Ctx) error {
 						return ctx.Next()
 					}
 
-					var (
-						eg = new(errgroup.Group)
-						mu = new(sync.Mutex)
-					)
-
-					eg.Go(func() error {
-						defer mu.Unlock()
-
-						mu.Lock()
-						for _, fileData := range filesData {
-							if err = store.DeleteObject(storeCtx, fileData.Name); err != nil {
-								return err
-							}
-						}
-						return nil
-					})
+					filePaths := make([]string, 0, len(filesData))
+					for _, fileData := range filesData {
+						filePaths = append(filePaths, fileData.Name)
+					}
 
-					utils.LogErr(eg.Wait())
+					utils.LogErr(store.DeleteObjects(storeCtx, filePaths))
+					utils.LogErr(store.SweepTrash(storeCtx, username, true))
 
 				}
 			} else {
@@ -68,32 +82,23 @@ func AutoDeleteScheduler(ctx *fiber.Ctx) error {
 	storeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filesData, err := store.ListObjects(storeCtx, ctx.Params("username"))
+	filesData, err := store.ListObjects(storeCtx, ctx.Params("username"), func(data *models.DataFile) bool {
+		return data.AutoDeleteAt < time.Now().UnixMilli()
+	})
 	if err != nil {
 		log.Error(err)
 		return ctx.Next()
 	}
 
-	var (
-		mu = new(sync.Mutex)
-		eg = new(errgroup.Group)
-	)
-
-	eg.Go(func() error {
-		defer mu.Unlock()
+	filePaths := make([]string, 0, len(filesData))
+	for _, fileData := range filesData {
+		filePaths = append(filePaths, fileData.Name)
+	}
 
-		mu.Lock()
-		for _, fileData := range filesData {
-			if fileData.AutoDeleteAt < time.Now().UnixMilli() {
-				if err = store.DeleteObject(storeCtx, fileData.Name); err != nil {
-					return err
-				}
-			}
-		}
-		return nil
-	})
+	utils.LogErr(store.DeleteObjects(storeCtx, filePaths))
 
-	utils.LogErr(eg.Wait())
+	// trash is deleted permanently after retention
+	utils.LogErr(store.SweepTrash(storeCtx, ctx.Params("username"), false))
 
 	return ctx.Next()
 }
@@ -101,4 +106,8 @@ func AutoDeleteScheduler(ctx *fiber.Ctx) error {
 var Cache = cache.New(cache.Config{
 	Expiration:   10 * time.Second,
 	CacheControl: true,
+	// evaluated after handler, response that not allowed to be stored (e.g. password protected file) is skipped
+	Next: func(ctx *fiber.Ctx) bool {
+		return strings.Contains(string(ctx.Response().Header.Peek(fiber.HeaderCacheControl)), "no-store")
+	},
 })
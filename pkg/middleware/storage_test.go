@@ -112,3 +112,38 @@ func TestPurgeAnonymousAccount(test *testing.T) {
 		})
 	}
 }
+
+func TestCheckFilesPath(test *testing.T) {
+	app := fiber.New()
+
+	group := app.Group("/files/:username", CheckFilesPath)
+	group.Get("/public/:filename", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(fiber.StatusOK)
+	})
+
+	testsTables := []struct {
+		name       string
+		path       string
+		statusCode int
+	}{
+		{"TestOK", "/files/alice/public/hello%20world.txt", fiber.StatusOK},
+		{"TestOnRecordPrefix", "/files/.tempsy/public/hello.txt", fiber.StatusNotFound},
+		{"TestOnEncodedDotUsername", "/files/%2Etempsy/public/hello.txt", fiber.StatusNotFound},
+		{"TestOnEncodedSlash", "/files/alice/public/trash%2Falice%2F123", fiber.StatusBadRequest},
+		{"TestOnEncodedBackslash", "/files/alice/public/a%5Cb.txt", fiber.StatusBadRequest},
+		{"TestOnEncodedSlashUsername", "/files/alice%2F..%2F.tempsy/public/hello.txt", fiber.StatusBadRequest},
+	}
+
+	for _, table := range testsTables {
+		test.Run(table.name, func(test *testing.T) {
+			res, err := app.Test(httptest.NewRequest(fiber.MethodGet, table.path, nil))
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			require.Equal(test, table.statusCode, res.StatusCode)
+		})
+	}
+}
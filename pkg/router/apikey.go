@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleCreateApiKey create api key of the user, the key is only returned once in the response
+func HandleCreateApiKey(ctx *fiber.Ctx) error {
+	user := identity.FromCtx(ctx)
+
+	// guest account is temporary, api key would outlive it
+	if user.Guest {
+		return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        "forbidden",
+				Description: "Guest account cannot create api key",
+			},
+		})
+	}
+
+	apiKey := new(models.ApiKey)
+	if err := ctx.BodyParser(apiKey); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidBody,
+				Description: "Body must be json object of api key, eg: {\"name\": \"ci\", \"scopes\": [\"read\", \"upload\"]}",
+			},
+		})
+	}
+
+	if err := store.ValidateApiKey(apiKey); err != nil {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidApiKey,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	record, token, err := store.CreateApiKey(storeCtx, user.UserName, apiKey)
+	if err != nil {
+		if errors.Is(err, store.ErrorTooManyApiKeys) {
+			return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeTooManyApiKeys,
+					Description: utils.Describe(err.Error()),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	created := record.ApiKey()
+	created.Key = token
+
+	return ctx.Status(fiber.StatusCreated).JSON(created)
+}
+
+// HandleListApiKeys return all api key of the user without the secret
+func HandleListApiKeys(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	records, err := store.ListApiKeys(storeCtx, identity.FromCtx(ctx).UserName)
+	utils.Check(err)
+
+	apiKeys := make([]*models.ApiKey, 0, len(records))
+	for _, record := range records {
+		apiKeys = append(apiKeys, record.ApiKey())
+	}
+
+	return ctx.JSON(apiKeys)
+}
+
+func HandleRevokeApiKey(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if err := store.RevokeApiKey(storeCtx, identity.FromCtx(ctx).UserName, ctx.Params("id")); err != nil {
+		if errors.Is(err, store.ErrorApiKeyNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeApiKeyNotFound,
+					Description: utils.Describe(err.Error()),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCreateApiKey(test *testing.T) {
+	app := fiber.New()
+
+	// authenticated by middleware
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals(identity.LocalKey, &identity.Identity{
+			UserName: ctx.Get("test-username"),
+			Guest:    strings.HasPrefix(ctx.Get("test-username"), "tempsyanonym-"),
+		})
+
+		return ctx.Next()
+	})
+
+	app.Post("/auth/keys", HandleCreateApiKey)
+
+	tableTests := []struct {
+		name     string
+		username string
+		body     string
+		status   int
+		errType  string
+	}{
+		{
+			name:     "TestOnGuest",
+			username: "tempsyanonym-1-test",
+			body:     `{"name": "ci", "scopes": ["read"]}`,
+			status:   fiber.StatusForbidden,
+			errType:  "forbidden",
+		},
+		{
+			name:     "TestOnInvalidBody",
+			username: "test",
+			body:     `["ci"]`,
+			status:   fiber.StatusBadRequest,
+			errType:  utils.ErrorTypeInvalidBody,
+		},
+		{
+			name:     "TestOnInvalidScope",
+			username: "test",
+			body:     `{"name": "ci", "scopes": ["admin"]}`,
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidApiKey,
+		},
+		{
+			name:     "TestOnExpired",
+			username: "test",
+			body:     `{"name": "ci", "scopes": ["read"], "expiresAt": 1}`,
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidApiKey,
+		},
+	}
+
+	for _, tableTest := range tableTests {
+		test.Run(tableTest.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/auth/keys", strings.NewReader(tableTest.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set("test-username", tableTest.username)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			require.Equal(test, tableTest.status, res.StatusCode)
+
+			resBody, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(resBody, apiErr))
+
+			assert.Equal(test, tableTest.errType, apiErr.Error.Kind)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleDownloadArchive stream archive of files selected by names or listing filter
+func HandleDownloadArchive(ctx *fiber.Ctx) error {
+	return sendArchive(ctx, false)
+}
+
+// HandleDownloadPublicArchive stream archive of public files, protected and limited download file is excluded
+// since archive cannot be unlocked or counted per file
+func HandleDownloadPublicArchive(ctx *fiber.Ctx) error {
+	return sendArchive(ctx, true)
+}
+
+func sendArchive(ctx *fiber.Ctx, publicOnly bool) error {
+	var (
+		username = ctx.Params("username")
+		// copied, since it is used after handler is returned
+		format = strings.Clone(ctx.Query("format", store.ArchiveFormatZip))
+	)
+
+	contentType, err := store.ArchiveContentType(format)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidArchive,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	filter, err := parseFileFilter(ctx)
+	if err != nil {
+		return invalidFilter(ctx, err)
+	}
+
+	names := make(map[string]bool)
+	if rawNames := ctx.Query("names"); rawNames != "" {
+		for _, name := range strings.Split(rawNames, ",") {
+			fileName, err := store.ValidateFileName(name)
+			if err != nil {
+				return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+					Error: &models.Error{
+						Kind:        utils.ErrorTypeInvalidFileName,
+						Description: utils.Describe(err.Error()),
+					},
+				})
+			}
+			names[fileName] = true
+		}
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	// trailing slash, so it's not matched other user with the same prefix
+	filesData, err := store.ListObjects(storeCtx, username+"/", func(data *models.DataFile) bool {
+		if publicOnly && (!data.IsPublic || data.PasswordHash != "" || data.MaxDownloads > 0) {
+			return false
+		}
+
+		if len(names) > 0 && !names[strings.TrimPrefix(data.Name, username+"/")] {
+			return false
+		}
+
+		return filter(data)
+	})
+	utils.Check(err)
+
+	if len(filesData) == 0 || (len(names) > 0 && len(filesData) != len(names)) {
+		errType := utils.ErrorTypeFileNotFound
+		if publicOnly {
+			errType = utils.ErrorTypeFileNotPublic
+		}
+
+		return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        errType,
+				Description: "Some or all of selected files, Is Not Found",
+			},
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, username, format))
+	// archive is built on the fly and may contain private file
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	// stream is written after handler is returned, so it cannot use context of the handler
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		archiveCtx, cancel := context.WithTimeout(context.Background(), store.ArchiveTimeout)
+		defer cancel()
+
+		// status is already sent, the client get truncated archive
+		if err := store.WriteArchive(archiveCtx, w, format, filesData); err != nil {
+			log.Error(err)
+		}
+
+		utils.LogErr(w.Flush())
+	})
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleDownloadArchive(test *testing.T) {
+	app := fiber.New()
+
+	app.Get("/:username/archive", HandleDownloadArchive)
+	app.Get("/:username/public/archive", HandleDownloadPublicArchive)
+
+	tableTests := []struct {
+		name    string
+		url     string
+		errType string
+	}{
+		{
+			name:    "TestOnInvalidFormat",
+			url:     "/test-archive/archive?format=rar",
+			errType: utils.ErrorTypeInvalidArchive,
+		},
+		{
+			name:    "TestOnInvalidFilter",
+			url:     "/test-archive/archive?max_size=invalid",
+			errType: utils.ErrorTypeInvalidFilter,
+		},
+		{
+			name:    "TestOnInvalidName",
+			url:     "/test-archive/public/archive?names=ok.txt,invalid",
+			errType: utils.ErrorTypeInvalidFileName,
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, table.url, nil)
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, fiber.StatusBadRequest, res.StatusCode)
+			assert.Equal(test, table.errType, apiErr.Error.Kind)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// overridableHeaders file metadata that can be changed when file is copied or moved,
+// end-to-end header is bound to the content, so it cannot be changed
+var overridableHeaders = []string{
+	store.HeaderAutoDeleteAt,
+	store.HeaderPrivateUrlExpires,
+	store.HeaderIsPublic,
+	store.HeaderMaxDownloads,
+	store.HeaderMaxVersions,
+}
+
+// HandleCopyFile duplicate file to new name without re-uploading
+func HandleCopyFile(ctx *fiber.Ctx) error {
+	return copyFile(ctx, false)
+}
+
+// HandleMoveFile rename file with its versions without re-uploading
+func HandleMoveFile(ctx *fiber.Ctx) error {
+	return copyFile(ctx, true)
+}
+
+func copyFile(ctx *fiber.Ctx, move bool) error {
+	var (
+		username = ctx.Params("username")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", username, fileName)
+	)
+
+	dstName, err := store.ValidateFileName(store.DecodeFileName(ctx.Get(store.HeaderFileName)))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidFileName,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+	dstPath := fmt.Sprintf("%s/%s", username, dstName)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	file, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fileNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	fileMetadata, copyErr := parseCopyHeader(ctx, file)
+	if copyErr != nil {
+		return ctx.Status(copyErr.status).JSON(&models.ApiError{Error: copyErr.Error})
+	}
+
+	if move {
+		err = store.MoveObject(storeCtx, filePath, dstPath, fileMetadata)
+	} else {
+		if err = store.CheckOrgQuota(storeCtx, store.OrgFromCtx(ctx), 1, file.Size); err != nil {
+			return orgQuotaExceeded(ctx, err)
+		}
+
+		err = store.CopyObject(storeCtx, filePath, dstPath, fileMetadata)
+	}
+
+	if err != nil {
+		if store.IsPreconditionFailed(err) {
+			return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileExists,
+					Description: fmt.Sprintf("File: %s Already Exists", dstName),
+				},
+			})
+		}
+
+		// deleted while copied
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fileNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	fileData, err := store.GetObject(storeCtx, dstPath)
+	utils.Check(err)
+
+	status := fiber.StatusCreated
+	if move {
+		status = fiber.StatusOK
+	}
+
+	store.Format(fileData)
+	return ctx.Status(status).JSON(&fileData)
+}
+
+// parseCopyHeader return file metadata of the source, overridden by file header that is sent
+func parseCopyHeader(ctx *fiber.Ctx, file *models.DataFile) (*models.DataFile, *uploadError) {
+	var (
+		fileHeader = store.MapFileHeader(ctx.GetReqHeaders())
+		metadata   = map[string]string{
+			store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", file.AutoDeleteAt),
+			store.HeaderPrivateUrlExpires: fmt.Sprintf("%d", file.PrivateUrlExpires),
+			store.HeaderIsPublic:          fmt.Sprintf("%t", file.IsPublic),
+			store.HeaderMaxDownloads:      fmt.Sprintf("%d", file.MaxDownloads),
+			store.HeaderMaxVersions:       fmt.Sprintf("%d", file.MaxVersions),
+			store.HeaderE2eHeader:         file.E2eHeader,
+			store.MetadataPasswordHash:    file.PasswordHash,
+		}
+		fileMetadata = &models.DataFile{MimeType: file.MimeType}
+	)
+
+	for _, key := range overridableHeaders {
+		if value := fileHeader.Get(key); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	err := store.UnmarshalMetadata(metadata, fileMetadata)
+	if err != nil {
+		return nil, &uploadError{
+			status: fiber.StatusUnprocessableEntity,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	// empty password remove the protection
+	if password, ok := fileHeader[store.HeaderPassword]; ok {
+		if fileMetadata.PasswordHash, err = store.HashPassword(password); err != nil {
+			return nil, &uploadError{
+				status: fiber.StatusUnprocessableEntity,
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeInvalidHeaderFile,
+					Description: utils.Describe(err.Error()),
+				},
+			}
+		}
+	}
+
+	if err = validateExpiry(fileMetadata.PrivateUrlExpires, fileMetadata.AutoDeleteAt); err != nil {
+		return nil, &uploadError{
+			status: fiber.StatusUnprocessableEntity,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	return fileMetadata, nil
+}
+
+func fileNotFound(ctx *fiber.Ctx, fileName string) error {
+	return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeFileNotFound,
+			Description: fmt.Sprintf("File: %s, Is Not Found", fileName),
+		},
+	})
+}
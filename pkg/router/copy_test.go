@@ -0,0 +1,67 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleCopyFile(test *testing.T) {
+	app := fiber.New()
+
+	app.Post("/:username/:filename/copy", HandleCopyFile)
+	app.Post("/:username/:filename/move", HandleMoveFile)
+
+	tableTests := []struct {
+		name    string
+		url     string
+		dstName string
+	}{
+		{
+			name:    "TestOnCopyInvalidName",
+			url:     "/test-copy/ok.txt/copy",
+			dstName: "invalid",
+		},
+		{
+			name:    "TestOnMoveEmptyName",
+			url:     "/test-copy/ok.txt/move",
+			dstName: "",
+		},
+		{
+			name:    "TestOnMovePathSeparator",
+			url:     "/test-copy/ok.txt/move",
+			dstName: "..%2Fother%2Fok.txt",
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, table.url, nil)
+			req.Header.Set(store.HeaderFileName, table.dstName)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, fiber.StatusBadRequest, res.StatusCode)
+			assert.Equal(test, utils.ErrorTypeInvalidFileName, apiErr.Error.Kind)
+		})
+	}
+}
@@ -2,9 +2,9 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"sync"
 
 	"cloud.google.com/go/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
@@ -12,12 +12,11 @@ import (
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"golang.org/x/sync/errgroup"
 )
 
 func HandleDeleteFile(ctx *fiber.Ctx) error {
 	var (
-		fileName = ctx.Params("filename")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
 		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
 	)
 
@@ -37,50 +36,148 @@ func HandleDeleteFile(ctx *fiber.Ctx) error {
 		utils.Check(err)
 	}
 
-	utils.Check(store.DeleteObject(storeCtx, filePath))
+	utils.Check(store.TrashObject(storeCtx, filePath))
 
 	return ctx.SendStatus(fiber.StatusNoContent)
 }
 
+type deleteFilesBody struct {
+	Names []string `json:"names"`
+}
+
+// HandleDeleteAllFile delete files matched by listing filter or explicit list of names in body,
+// all files is deleted if there is no filter
 func HandleDeleteAllFile(ctx *fiber.Ctx) error {
 	username := ctx.Params("username")
 
+	if err := checkFilterQuery(ctx, "dry_run"); err != nil {
+		return invalidFilter(ctx, err)
+	}
+
+	filter, err := parseFileFilter(ctx)
+	if err != nil {
+		return invalidFilter(ctx, err)
+	}
+
+	body := new(deleteFilesBody)
+	if len(ctx.Body()) > 0 {
+		if err = json.Unmarshal(ctx.Body(), body); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeInvalidBody,
+					Description: "Body must be json object with list of file names, eg: {\"names\": [\"example.txt\"]}",
+				},
+			})
+		}
+	}
+
 	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
 	defer cancel()
 
-	filesData, err := store.ListObjects(storeCtx, username)
+	// trailing slash, so it's not matched other user with the same prefix
+	filesData, err := store.ListObjects(storeCtx, username+"/", filter)
 	utils.Check(err)
 
-	if len(filesData) == 0 {
-		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
-			Error: &models.Error{
-				Kind:        utils.ErrorTypeEmptyData,
-				Description: "Cannot delete empty data files, no data for user: " + username,
-			},
-		})
-	}
-
 	var (
-		eg = new(errgroup.Group)
-		mu = new(sync.Mutex)
+		results = make([]*models.FileResult, 0, len(filesData))
+		targets = make([]*models.FileResult, 0, len(filesData))
+		dryRun  = ctx.QueryBool("dry_run")
 	)
 
-	eg.Go(func() error {
-		defer mu.Unlock()
+	if len(body.Names) > 0 {
+		var (
+			matched = make(map[string]bool, len(filesData))
+			seen    = make(map[string]bool, len(body.Names))
+		)
 
-		mu.Lock()
 		for _, fileData := range filesData {
-			if err = store.DeleteObject(storeCtx, fileData.Name); err != nil {
-				return err
+			store.Format(fileData)
+			matched[fileData.Name] = true
+		}
+
+		for _, name := range body.Names {
+			fileName, err := store.ValidateFileName(name)
+			if err == nil {
+				name = fileName
+			}
+
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			result := &models.FileResult{Name: name}
+			if err != nil {
+				result.Status = models.FileResultFailed
+				result.Error = &models.Error{
+					Kind:        utils.ErrorTypeInvalidFileName,
+					Description: utils.Describe(err.Error()),
+				}
+			} else if !matched[name] {
+				result.Status = models.FileResultNotFound
+			} else {
+				targets = append(targets, result)
 			}
+
+			results = append(results, result)
+		}
+	} else {
+		// filter or dry run that match no files is empty result, not an error
+		if len(filesData) == 0 && !dryRun && !hasFilterQuery(ctx) {
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeEmptyData,
+					Description: "Cannot delete empty data files, no data for user: " + username,
+				},
+			})
 		}
 
-		return nil
-	})
+		for _, fileData := range filesData {
+			store.Format(fileData)
 
-	if err = eg.Wait(); err != nil {
-		log.Panic(err)
+			result := &models.FileResult{Name: fileData.Name}
+			targets = append(targets, result)
+			results = append(results, result)
+		}
 	}
 
-	return ctx.SendStatus(fiber.StatusNoContent)
+	if dryRun {
+		for _, target := range targets {
+			target.Status = models.FileResultDryRun
+		}
+	} else {
+		filePaths := make([]string, 0, len(targets))
+		for _, target := range targets {
+			filePaths = append(filePaths, fmt.Sprintf("%s/%s", username, target.Name))
+		}
+
+		err = store.TrashObjects(storeCtx, filePaths)
+		errs := store.BatchErrors(err)
+		if err != nil && errs == nil {
+			log.Panic(err)
+		}
+
+		for i, target := range targets {
+			switch {
+			case errs == nil || errs[i] == nil:
+				target.Status = models.FileResultDeleted
+			case errors.Is(errs[i], storage.ErrObjectNotExist):
+				// deleted by other request
+				target.Status = models.FileResultNotFound
+			default:
+				log.Error(errs[i])
+
+				target.Status = models.FileResultFailed
+				target.Error = &models.Error{
+					Kind:        "unknown_server_error",
+					Description: "Failed to delete file, please try again",
+				}
+			}
+		}
+	}
+
+	return ctx.JSON(&models.BulkDeleteResult{
+		DryRun:  dryRun,
+		Results: results,
+	})
 }
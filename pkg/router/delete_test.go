@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,6 +36,9 @@ func TestHandleDelete(test *testing.T) {
 		for _, dataFile := range dataFiles {
 			utils.LogErr(store.DeleteObject(storeCtx, dataFile.Name))
 		}
+
+		// deleted file is moved to trash
+		utils.LogErr(store.SweepTrash(storeCtx, username, true))
 	})
 
 	for i := 1; i <= 3; i++ {
@@ -80,17 +84,76 @@ func TestHandleDelete(test *testing.T) {
 	})
 
 	test.Run("TestHandleDeleteAll", func(test *testing.T) {
-		test.Run("TestOk", func(test *testing.T) {
-			req := httptest.NewRequest(fiber.MethodDelete, "/api/files/"+username, nil)
-			res, err := app.Test(req, 1500*10) // 15 seconds
-			require.NoError(test, err)
+		testCases := []struct {
+			name     string
+			query    string
+			body     string
+			statuses []string
+		}{
+			{
+				name:     "TestOnInvalidFilter",
+				query:    "?min_size=invalid",
+				statuses: nil,
+			},
+			{
+				name:     "TestOkDryRun",
+				query:    "?dry_run=true&name=example-*.txt",
+				statuses: []string{models.FileResultDryRun, models.FileResultDryRun},
+			},
+			{
+				name:     "TestOkFilterNoMatch",
+				query:    "?name=missing-*.txt",
+				statuses: []string{},
+			},
+			{
+				name:     "TestOkNames",
+				body:     `{"names": ["example-2.txt", "example-2.txt", "example-1.txt", "../example-3.txt"]}`,
+				statuses: []string{models.FileResultDeleted, models.FileResultNotFound, models.FileResultFailed},
+			},
+			{
+				name:     "TestOk",
+				statuses: []string{models.FileResultDeleted},
+			},
+			{
+				name:     "TestOkDryRunNoFiles",
+				query:    "?dry_run=true",
+				statuses: []string{},
+			},
+		}
 
-			test.Cleanup(func() {
-				utils.LogErr(res.Body.Close())
-			})
+		for _, tc := range testCases {
+			test.Run(tc.name, func(test *testing.T) {
+				req := httptest.NewRequest(fiber.MethodDelete, "/api/files/"+username+tc.query, strings.NewReader(tc.body))
+				res, err := app.Test(req, 1500*10) // 15 seconds
+				require.NoError(test, err)
 
-			assert.Equal(test, fiber.StatusNoContent, res.StatusCode)
-		})
+				test.Cleanup(func() {
+					utils.LogErr(res.Body.Close())
+				})
+
+				body, err := io.ReadAll(res.Body)
+				require.NoError(test, err)
+
+				if tc.statuses == nil {
+					apiErr := new(models.ApiError)
+					require.NoError(test, json.Unmarshal(body, &apiErr))
+
+					assert.Equal(test, fiber.StatusBadRequest, res.StatusCode)
+					assert.Equal(test, utils.ErrorTypeInvalidFilter, apiErr.Error.Kind)
+					return
+				}
+
+				result := new(models.BulkDeleteResult)
+				require.NoError(test, json.Unmarshal(body, &result))
+
+				assert.Equal(test, fiber.StatusOK, res.StatusCode)
+				require.Len(test, result.Results, len(tc.statuses))
+
+				for i, status := range tc.statuses {
+					assert.Equal(test, status, result.Results[i].Status)
+				}
+			})
+		}
 
 		test.Run("TestOnEmptyData", func(test *testing.T) {
 			req := httptest.NewRequest(fiber.MethodDelete, "/api/files/"+username, nil)
@@ -0,0 +1,153 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// extractUpload unpack uploaded archive into individual files with the same metadata as the upload,
+// all entries is validated before any file is uploaded. File names is prefixed with query folder when it's set
+func extractUpload(ctx *fiber.Ctx, fileMetadata *models.DataFile) error {
+	username := ctx.Params("username")
+
+	// content is cipher text, cannot be read by the server
+	if fileMetadata.E2eHeader != "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: "End-to-end encrypted archive cannot be extracted",
+			},
+		})
+	}
+
+	if !store.IsExtractable(fileMetadata.MimeType) {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeUnsupportedType,
+				Description: utils.Describe(store.ErrorUnsupportedArchive.Error()) + ": " + fileMetadata.MimeType,
+			},
+		})
+	}
+
+	files, err := store.ExtractArchive(fileMetadata.MimeType, ctx.Body())
+	if err != nil {
+		status, errType := fiber.StatusBadRequest, utils.ErrorTypeInvalidArchive
+		if errors.Is(err, store.ErrorTooManyEntries) || errors.Is(err, store.ErrorArchiveTooLarge) {
+			status, errType = fiber.StatusRequestEntityTooLarge, utils.ErrorTypeArchiveTooLarge
+		}
+
+		description := utils.Describe(err.Error())
+		// flattened name is kept as it is
+		if errors.Is(err, store.ErrorDuplicateArchiveEntry) {
+			description = utils.Describe(store.ErrorDuplicateArchiveEntry.Error()) + strings.TrimPrefix(err.Error(), store.ErrorDuplicateArchiveEntry.Error())
+		}
+
+		return ctx.Status(status).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        errType,
+				Description: description,
+			},
+		})
+	}
+
+	var (
+		folder     = ctx.Query("folder")
+		mimePolicy = store.MimePolicyFor(store.AccountTierOf(username))
+		filesData  = make([]*models.DataFile, len(files))
+		results    = make([]*models.FileResult, len(files))
+	)
+
+	for i, file := range files {
+		name := file.Name
+		if folder != "" {
+			name = folder + store.ExtractSeparator + name
+		}
+
+		fileName, err := store.ValidateFileName(name)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeInvalidFileName,
+					Description: fmt.Sprintf("%s: %s", utils.Describe(err.Error()), name),
+				},
+			})
+		}
+
+		contentType := store.DetectContentType(fileName, file.Content)
+		if !mimePolicy.Allows(contentType) {
+			return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeUnsupportedType,
+					Description: fmt.Sprintf("Unsupported Content-Type: %s, of File: %s", contentType, fileName),
+				},
+			})
+		}
+
+		if contentType, err = store.VerifyContentType(mimePolicy, contentType, file.Content); err != nil {
+			return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeContentMismatch,
+					Description: fmt.Sprintf("%s, of File: %s", utils.Describe(err.Error()), fileName),
+				},
+			})
+		}
+
+		fileData := *fileMetadata
+		fileData.MimeType = contentType
+
+		filesData[i] = &fileData
+		results[i] = &models.FileResult{Name: fileName}
+	}
+
+	// many files is uploaded, it take longer than single upload
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.ArchiveTimeout)
+	defer cancel()
+
+	var size int64
+	for _, file := range files {
+		size += int64(len(file.Content))
+	}
+
+	if err = store.CheckOrgQuota(storeCtx, store.OrgFromCtx(ctx), len(files), size); err != nil {
+		return orgQuotaExceeded(ctx, err)
+	}
+
+	err = store.Batch(storeCtx, files, func(ctx context.Context, i int, file *store.ExtractedFile) error {
+		return store.UploadObject(ctx, fmt.Sprintf("%s/%s", username, results[i].Name), file.Content, filesData[i])
+	})
+	errs := store.BatchErrors(err)
+	if err != nil && errs == nil {
+		log.Panic(err)
+	}
+
+	for i, result := range results {
+		switch {
+		case errs == nil || errs[i] == nil:
+			result.Status = models.FileResultCreated
+		case store.IsPreconditionFailed(errs[i]):
+			result.Status = models.FileResultFailed
+			result.Error = &models.Error{
+				Kind:        utils.ErrorTypeFileExists,
+				Description: fmt.Sprintf("File: %s Already Exists", result.Name),
+			}
+		default:
+			log.Error(errs[i])
+
+			result.Status = models.FileResultFailed
+			result.Error = &models.Error{
+				Kind:        "unknown_server_error",
+				Description: "Failed to upload file, please try again",
+			}
+		}
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(&models.ExtractResult{Results: results})
+}
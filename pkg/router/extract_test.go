@@ -0,0 +1,107 @@
+package router
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipArchive(test *testing.T, entries map[string][]byte) []byte {
+	var (
+		buf       = new(bytes.Buffer)
+		zipWriter = zip.NewWriter(buf)
+	)
+
+	for name, content := range entries {
+		w, err := zipWriter.Create(name)
+		require.NoError(test, err)
+
+		_, err = w.Write(content)
+		require.NoError(test, err)
+	}
+	require.NoError(test, zipWriter.Close())
+
+	return buf.Bytes()
+}
+
+func TestExtractUpload(test *testing.T) {
+	app := fiber.New()
+
+	app.Post("/api/files/:username", HandleUploadFile)
+
+	tableErrs := []struct {
+		name        string
+		contentType string
+		file        []byte
+		errType     string
+		statusCode  int
+	}{
+		{
+			name:        "TestOnNotArchive",
+			contentType: fiber.MIMETextPlainCharsetUTF8,
+			file:        []byte(test.Name()),
+			errType:     utils.ErrorTypeUnsupportedType,
+			statusCode:  fiber.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "TestOnZipSlip",
+			contentType: "application/zip",
+			file:        zipArchive(test, map[string][]byte{"../evil.txt": []byte(test.Name())}),
+			errType:     utils.ErrorTypeInvalidArchive,
+			statusCode:  fiber.StatusBadRequest,
+		},
+		{
+			name:        "TestOnInvalidEntryName",
+			contentType: "application/zip",
+			file:        zipArchive(test, map[string][]byte{"reports/noext": []byte(test.Name())}),
+			errType:     utils.ErrorTypeInvalidFileName,
+			statusCode:  fiber.StatusBadRequest,
+		},
+		{
+			name:        "TestOnUnsupportedEntry",
+			contentType: "application/zip",
+			file:        zipArchive(test, map[string][]byte{"reports/data.unknownext": {0x00, 0x01, 0x02}}),
+			errType:     utils.ErrorTypeUnsupportedType,
+			statusCode:  fiber.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, table := range tableErrs {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/api/files/extract-test?extract=true", bytes.NewReader(table.file))
+			req.Header.Set(store.HeaderFileName, "reports.zip")
+			req.Header.Set(fiber.HeaderContentType, table.contentType)
+			req.Header.Set(store.HeaderIsPublic, "1")
+			req.Header.Set(store.HeaderAutoDeleteAt, fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()))
+			req.Header.Set(store.HeaderPrivateUrlExpires, "10") // 10 seconds
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, table.statusCode, res.StatusCode)
+			assert.Equal(test, table.errType, apiErr.Error.Kind)
+		})
+	}
+}
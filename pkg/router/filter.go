@@ -0,0 +1,151 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	ErrorInvalidFilter = errors.New("invalid_filter_query")
+	ErrorUnknownFilter = errors.New("unknown_filter_query")
+
+	filterQueries = []string{"name", "mime_type", "is_public", "size", "min_size", "max_size", "uploaded_before"}
+)
+
+// parseFileFilter build filter from query, shared by listing and bulk delete.
+// name support glob pattern (`*`, `?`, `[...]`), otherwise it's match by substring
+func parseFileFilter(ctx *fiber.Ctx) (func(data *models.DataFile) bool, error) {
+	var (
+		name     = ctx.Query("name")
+		mimeType = ctx.Query("mime_type")
+		isPublic = ctx.Query("is_public")
+	)
+
+	if strings.ContainsAny(name, "*?[") {
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("%w: name", ErrorInvalidFilter)
+		}
+	}
+
+	public, err := strconv.ParseBool(isPublic)
+	if isPublic != "" && err != nil {
+		return nil, fmt.Errorf("%w: is_public", ErrorInvalidFilter)
+	}
+
+	size, err := queryInt64(ctx, "size")
+	if err != nil {
+		return nil, err
+	}
+
+	minSize, err := queryInt64(ctx, "min_size")
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize, err := queryInt64(ctx, "max_size")
+	if err != nil {
+		return nil, err
+	}
+
+	uploadedBefore, err := queryInt64(ctx, "uploaded_before")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(data *models.DataFile) bool {
+		if size > 0 && size != data.Size {
+			return false
+		}
+
+		if minSize > 0 && data.Size < minSize {
+			return false
+		}
+
+		if maxSize > 0 && data.Size > maxSize {
+			return false
+		}
+
+		if uploadedBefore > 0 && data.UploadedAt >= uploadedBefore {
+			return false
+		}
+
+		if isPublic != "" && public != data.IsPublic {
+			return false
+		}
+
+		if mimeType != "" && !strings.Contains(data.MimeType, mimeType) {
+			return false
+		}
+
+		if name != "" {
+			fileName := data.Name
+			if split := strings.SplitN(fileName, "/", 2); len(split) > 1 {
+				fileName = split[1]
+			}
+
+			if strings.ContainsAny(name, "*?[") {
+				matched, _ := path.Match(name, fileName)
+				return matched
+			}
+
+			return strings.Contains(fileName, name)
+		}
+
+		return true
+	}, nil
+}
+
+// checkFilterQuery reject query that is not a filter or one of queries of the route,
+// so misspelled filter is never ignored by destructive route, e.g. bulk delete
+func checkFilterQuery(ctx *fiber.Ctx, queries ...string) error {
+	for key := range ctx.Queries() {
+		if !slices.Contains(filterQueries, key) && !slices.Contains(queries, key) {
+			return fmt.Errorf("%w: %s", ErrorUnknownFilter, key)
+		}
+	}
+
+	return nil
+}
+
+// hasFilterQuery report whether any filter query is set, even with empty value
+func hasFilterQuery(ctx *fiber.Ctx) bool {
+	for key := range ctx.Queries() {
+		if slices.Contains(filterQueries, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryInt64 return 0 if query is empty
+func queryInt64(ctx *fiber.Ctx, key string) (int64, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	num, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrorInvalidFilter, key)
+	}
+
+	return num, nil
+}
+
+func invalidFilter(ctx *fiber.Ctx, err error) error {
+	return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeInvalidFilter,
+			Description: utils.Describe(err.Error()),
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileFilter(test *testing.T) {
+	var (
+		app       = fiber.New()
+		filesData = []*models.DataFile{
+			{
+				Name:       "test-filter/report-2024.pdf",
+				MimeType:   "application/pdf",
+				Size:       2048,
+				UploadedAt: 1000,
+				IsPublic:   true,
+			},
+			{
+				Name:       "test-filter/notes.txt",
+				MimeType:   fiber.MIMETextPlainCharsetUTF8,
+				Size:       10,
+				UploadedAt: 3000,
+			},
+			{
+				Name:       "test-filter/report.txt",
+				MimeType:   fiber.MIMETextPlainCharsetUTF8,
+				Size:       512,
+				UploadedAt: 2000,
+			},
+		}
+	)
+
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		filter, err := parseFileFilter(ctx)
+		if err != nil {
+			return invalidFilter(ctx, err)
+		}
+
+		names := make([]string, 0)
+		for _, fileData := range filesData {
+			if filter(fileData) {
+				names = append(names, fileData.Name)
+			}
+		}
+
+		return ctx.JSON(&names)
+	})
+
+	tableTests := []struct {
+		name  string
+		query string
+		names []string
+	}{
+		{
+			name:  "TestOkEmpty",
+			query: "",
+			names: []string{"test-filter/report-2024.pdf", "test-filter/notes.txt", "test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkNameContains",
+			query: "?name=report",
+			names: []string{"test-filter/report-2024.pdf", "test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkNamePattern",
+			query: "?name=*.txt",
+			names: []string{"test-filter/notes.txt", "test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkMimeType",
+			query: "?mime_type=text/plain",
+			names: []string{"test-filter/notes.txt", "test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkSizeRange",
+			query: "?min_size=100&max_size=1024",
+			names: []string{"test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkSize",
+			query: "?size=10",
+			names: []string{"test-filter/notes.txt"},
+		},
+		{
+			name:  "TestOkUploadedBefore",
+			query: "?uploaded_before=3000",
+			names: []string{"test-filter/report-2024.pdf", "test-filter/report.txt"},
+		},
+		{
+			name:  "TestOkPrivate",
+			query: "?is_public=false&name=report",
+			names: []string{"test-filter/report.txt"},
+		},
+		{
+			name:  "TestOnInvalidSize",
+			query: "?min_size=-1",
+		},
+		{
+			name:  "TestOnInvalidPublic",
+			query: "?is_public=maybe",
+		},
+		{
+			name:  "TestOnInvalidPattern",
+			query: "?name=[report",
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/"+table.query, nil)
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			if table.names == nil {
+				apiErr := new(models.ApiError)
+				require.NoError(test, json.Unmarshal(body, &apiErr))
+
+				assert.Equal(test, fiber.StatusBadRequest, res.StatusCode)
+				assert.Equal(test, utils.ErrorTypeInvalidFilter, apiErr.Error.Kind)
+				return
+			}
+
+			var names []string
+			require.NoError(test, json.Unmarshal(body, &names))
+
+			assert.Equal(test, fiber.StatusOK, res.StatusCode)
+			assert.Equal(test, table.names, names)
+		})
+	}
+}
+
+func TestCheckFilterQuery(test *testing.T) {
+	app := fiber.New()
+	app.Delete("/", func(ctx *fiber.Ctx) error {
+		if err := checkFilterQuery(ctx, "dry_run"); err != nil {
+			return invalidFilter(ctx, err)
+		}
+		return ctx.SendStatus(fiber.StatusNoContent)
+	})
+
+	for query, statusCode := range map[string]int{
+		"":                                   fiber.StatusNoContent,
+		"?mime_type=text/plain&dry_run=true": fiber.StatusNoContent,
+		"?mime_typ=text/plain":               fiber.StatusBadRequest,
+		"?name=*.txt&dryrun=true":            fiber.StatusBadRequest,
+	} {
+		res, err := app.Test(httptest.NewRequest(fiber.MethodDelete, "/"+query, nil), 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, statusCode, res.StatusCode, query)
+	}
+}
@@ -4,9 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
-	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
@@ -21,7 +19,7 @@ func HandleGetPublicFile(ctx *fiber.Ctx) error {
 	defer cancel()
 
 	var (
-		fileName = ctx.Params("filename")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
 		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
 	)
 
@@ -46,27 +44,98 @@ func HandleGetPublicFile(ctx *fiber.Ctx) error {
 		})
 	}
 
-	agent := fiber.Get(fileData.Url)
-	agent.Timeout(10 * time.Second)
+	if errType := unlockPublicFile(ctx, filePath, fileData.PasswordHash); errType != "" {
+		return lockedPublicFile(ctx, errType)
+	}
 
-	statusCode, fileByte, errs := agent.Bytes()
-	if len(errs) > 0 {
-		utils.Check(errs[0])
+	isLastDownload := false
+	if fileData.MaxDownloads > 0 {
+		// each download must be counted, so it cannot be cached
+		ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+		// HEAD request does not download the file
+		if ctx.Method() != fiber.MethodHead {
+			isLastDownload, err = store.ConsumeDownload(storeCtx, filePath)
+			if err != nil {
+				if errors.Is(err, store.ErrorDownloadLimitReached) || errors.Is(err, storage.ErrObjectNotExist) {
+					return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+						Error: &models.Error{
+							Kind:        utils.ErrorTypeFileNotPublic,
+							Description: fmt.Sprintf("File: %s, Is Not Found Or Not Public", fileName),
+						},
+					})
+				}
+				log.Panic(err)
+			}
+		}
 	}
 
-	if statusCode != fiber.StatusOK {
-		log.Panic("Unknown Error in Service File")
+	fileByte, err := store.ReadObject(storeCtx, filePath)
+	utils.Check(err)
+
+	// burn after the last download
+	if isLastDownload {
+		utils.LogErr(store.DeleteObject(storeCtx, filePath))
 	}
 
-	ctx.Set(fiber.HeaderContentType, fileData.MimeType)
+	store.SetPublicHeaders(ctx, fileName, fileData.MimeType)
 	ctx.Set(fiber.HeaderContentLength, fmt.Sprintf("%d", len(fileByte))) // maybe unnecessary
+	store.SetDigestHeaders(ctx, fileByte)
+	if fileData.E2eHeader != "" {
+		ctx.Set(store.HeaderE2eHeader, fileData.E2eHeader)
+	}
+
+	return ctx.Send(fileByte)
+}
+
+// HandleDownloadFile download private file with url signed by the server
+func HandleDownloadFile(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		log.Panic(err)
+	}
+
+	// deleted file is treated as invalid signature, so existence of file is not leaked
+	if err == nil {
+		err = store.VerifyDownloadSignature(filePath, fileData.FileId, ctx.Query("exp"), ctx.Query("sig"))
+	}
+
+	if err != nil {
+		return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidSignature,
+				Description: "Download url is invalid or expired, please request new one",
+			},
+		})
+	}
+
+	// signed url is private, must not be stored by shared cache
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	fileByte, err := store.ReadObject(storeCtx, filePath)
+	utils.Check(err)
+
+	store.SetPublicHeaders(ctx, fileName, fileData.MimeType)
+	ctx.Set(fiber.HeaderContentLength, fmt.Sprintf("%d", len(fileByte)))
+	store.SetDigestHeaders(ctx, fileByte)
+	if fileData.E2eHeader != "" {
+		ctx.Set(store.HeaderE2eHeader, fileData.E2eHeader)
+	}
 
 	return ctx.Send(fileByte)
 }
 
 func HandleGetFileData(ctx *fiber.Ctx) error {
 	var (
-		fileName = ctx.Params("filename")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
 		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
 	)
 
@@ -99,22 +168,12 @@ func HandleListFilesData(ctx *fiber.Ctx) error {
 	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
 	defer cancel()
 
-	// TODO: Filter unit test
-	filesData, err := store.ListObjects(storeCtx, ctx.Params("username"), func(data *models.DataFile) bool {
-		if size := ctx.QueryInt("size"); size > 0 && int64(size) != data.Size {
-			return false
-		}
-
-		if name := ctx.Query("name"); name != "" && !strings.Contains(data.Name, name) {
-			return false
-		}
-
-		if mimeType := ctx.Query("mime_type"); mimeType != "" && !strings.Contains(data.MimeType, mimeType) {
-			return false
-		}
+	filter, err := parseFileFilter(ctx)
+	if err != nil {
+		return invalidFilter(ctx, err)
+	}
 
-		return true
-	})
+	filesData, err := store.ListObjects(storeCtx, ctx.Params("username"), filter)
 	utils.Check(err)
 
 	if limitMax := ctx.QueryInt("limit"); limitMax > 0 && limitMax < len(filesData) {
@@ -235,6 +235,8 @@ func TestHandleGetPublicFile(test *testing.T) {
 		assert.NotEmpty(test, body)
 		assert.Equal(test, fileByte, body)
 		assert.Equal(test, fiber.MIMETextPlainCharsetUTF8, res.Header.Get(fiber.HeaderContentType))
+		assert.Equal(test, "nosniff", res.Header.Get(fiber.HeaderXContentTypeOptions))
+		assert.Equal(test, store.PublicContentSecurityPolicy, res.Header.Get(fiber.HeaderContentSecurityPolicy))
 		assert.Equal(test, fmt.Sprintf("%d", len(body)), res.Header.Get(fiber.HeaderContentLength))
 		assert.Equal(test, fiber.StatusOK, res.StatusCode)
 	})
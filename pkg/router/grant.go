@@ -0,0 +1,221 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandlePutGrant give other user read or write access to the file, role of existing grant is replaced
+func HandlePutGrant(ctx *fiber.Ctx) error {
+	var (
+		username = ctx.Params("username")
+		grantee  = ctx.Params("grantee")
+		role     = ctx.Get(store.HeaderGrantRole)
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", username, fileName)
+	)
+
+	err := store.ValidateGrantee(username, grantee)
+	if err == nil {
+		err = store.ValidateGrantRole(role)
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderGrant,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fileNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	record := &store.GrantRecord{
+		FilePath: filePath,
+		FileId:   fileData.FileId,
+		Grantee:  grantee,
+		Role:     role,
+	}
+	utils.Check(store.PutGrant(storeCtx, record))
+
+	return ctx.JSON(record.Grant())
+}
+
+func HandleListGrants(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fileNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	records, err := store.ListGrants(storeCtx, filePath)
+	utils.Check(err)
+
+	grants := make([]*models.Grant, 0, len(records))
+	for _, record := range records {
+		// grant of previous file with the same name
+		if record.FileId != fileData.FileId {
+			continue
+		}
+
+		grants = append(grants, record.Grant())
+	}
+
+	return ctx.JSON(&grants)
+}
+
+// HandleListReceivedGrants list files of other users that the user has access to
+func HandleListReceivedGrants(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	records, err := store.ListReceivedGrants(storeCtx, ctx.Params("username"))
+	utils.Check(err)
+
+	filePaths := make([]string, 0, len(records))
+	for _, record := range records {
+		filePaths = append(filePaths, record.FilePath)
+	}
+
+	filesData, err := store.GetObjects(storeCtx, filePaths)
+	utils.Check(err)
+
+	fileIds := make(map[string]string, len(filesData))
+	for _, fileData := range filesData {
+		fileIds[fileData.Name] = fileData.FileId
+	}
+
+	grants := make([]*models.Grant, 0, len(records))
+	for _, record := range records {
+		// file is deleted, or deleted and uploaded again with the same name
+		if fileIds[record.FilePath] != record.FileId {
+			continue
+		}
+
+		grants = append(grants, record.Grant())
+	}
+
+	return ctx.JSON(&grants)
+}
+
+func HandleRevokeGrant(ctx *fiber.Ctx) error {
+	var (
+		grantee  = ctx.Params("grantee")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	err := store.ValidateGrantee(ctx.Params("username"), grantee)
+	if err == nil {
+		err = store.RevokeGrant(storeCtx, filePath, grantee)
+	}
+
+	if err != nil {
+		if errors.Is(err, store.ErrorGrantNotFound) || errors.Is(err, store.ErrorInvalidGrantee) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeGrantNotFound,
+					Description: fmt.Sprintf("Grant of User: %s to File: %s, Is Not Found", grantee, fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleTransferFile move file with its versions to other user, the new owner must agree by sending its token,
+// e.g. to move files of guest account into Google account after sign in. Grants of the file is revoked
+func HandleTransferFile(ctx *fiber.Ctx) error {
+	var (
+		username = ctx.Params("username")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", username, fileName)
+	)
+
+	// api key of the new owner is not accepted, since it's limited to its scopes
+	owner, err := identity.Identify(ctx.Get(auth.HeaderTransferAuthorization))
+	if errors.Is(err, identity.ErrorUnavailable) {
+		return identityUnavailable(ctx, err)
+	}
+	if err != nil || owner.ApiKey != nil {
+		utils.LogErr(err)
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidToken,
+				Description: "Token of the new owner is not valid, api key is not accepted",
+			},
+		})
+	}
+
+	if owner.UserName == username {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidTransfer,
+				Description: "New owner must be other user",
+			},
+		})
+	}
+
+	dstPath := fmt.Sprintf("%s/%s", owner.UserName, fileName)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if err = store.TransferObject(storeCtx, filePath, dstPath); err != nil {
+		if store.IsPreconditionFailed(err) {
+			return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileExists,
+					Description: fmt.Sprintf("File: %s Already Exists", fileName),
+				},
+			})
+		}
+
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return fileNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	utils.LogErr(store.RevokeGrants(storeCtx, filePath))
+
+	fileData, err := store.GetObject(storeCtx, dstPath)
+	utils.Check(err)
+
+	store.Format(fileData)
+	return ctx.JSON(&fileData)
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGrant(test *testing.T) {
+	app := fiber.New()
+
+	app.Put("/:username/:filename/grants/:grantee", HandlePutGrant)
+	app.Delete("/:username/:filename/grants/:grantee", HandleRevokeGrant)
+
+	tableTests := []struct {
+		name    string
+		method  string
+		url     string
+		role    string
+		status  int
+		errType string
+	}{
+		{
+			name:    "TestOnPutInvalidRole",
+			method:  fiber.MethodPut,
+			url:     "/test-grant/ok.txt/grants/other-user",
+			role:    "owner",
+			status:  fiber.StatusUnprocessableEntity,
+			errType: utils.ErrorTypeInvalidHeaderGrant,
+		},
+		{
+			name:    "TestOnPutOwner",
+			method:  fiber.MethodPut,
+			url:     "/test-grant/ok.txt/grants/test-grant",
+			role:    store.GrantRoleRead,
+			status:  fiber.StatusUnprocessableEntity,
+			errType: utils.ErrorTypeInvalidHeaderGrant,
+		},
+		{
+			name:    "TestOnRevokeInvalidGrantee",
+			method:  fiber.MethodDelete,
+			url:     "/test-grant/ok.txt/grants/other.user",
+			status:  fiber.StatusNotFound,
+			errType: utils.ErrorTypeGrantNotFound,
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(table.method, table.url, nil)
+			req.Header.Set(store.HeaderGrantRole, table.role)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, table.status, res.StatusCode)
+			assert.Equal(test, table.errType, apiErr.Error.Kind)
+		})
+	}
+}
+
+func TestHandleTransferFile(test *testing.T) {
+	app := fiber.New()
+
+	app.Post("/:username/:filename/transfer", HandleTransferFile)
+
+	username := guest.GenerateUsername()
+	token, err := guest.CreateToken(username)
+	require.NoError(test, err)
+
+	tableTests := []struct {
+		name          string
+		authorization string
+		errType       string
+	}{
+		{
+			name:    "TestOnMissingToken",
+			errType: utils.ErrorTypeInvalidToken,
+		},
+		{
+			name:          "TestOnSameOwner",
+			authorization: auth.BearerPrefix + token,
+			errType:       utils.ErrorTypeInvalidTransfer,
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/"+username+"/ok.txt/transfer", nil)
+			req.Header.Set(auth.HeaderTransferAuthorization, table.authorization)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, fiber.StatusBadRequest, res.StatusCode)
+			assert.Equal(test, table.errType, apiErr.Error.Kind)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleCreateOrg create organization with the user as the owner, files of organization is at namespace `org:<name>`
+func HandleCreateOrg(ctx *fiber.Ctx) error {
+	user := identity.FromCtx(ctx)
+
+	// guest account is temporary, organization would be left without owner
+	if user.Guest {
+		return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        "forbidden",
+				Description: "Guest account cannot create organization",
+			},
+		})
+	}
+
+	org := new(models.Org)
+	if err := ctx.BodyParser(org); err != nil {
+		return invalidOrgBody(ctx)
+	}
+
+	err := store.ValidateOrgName(org.Name)
+	if err == nil {
+		err = store.ValidateOrgPolicy(org)
+	}
+	if err != nil {
+		return invalidOrg(ctx, err)
+	}
+
+	org.Members = map[string]string{user.UserName: store.OrgRoleOwner}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if err = store.CreateOrg(storeCtx, org); err != nil {
+		if store.IsPreconditionFailed(err) {
+			return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeOrgExists,
+					Description: fmt.Sprintf("Organization: %s Already Exists", org.Name),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(org)
+}
+
+// HandleGetOrg return organization with its usage
+func HandleGetOrg(ctx *fiber.Ctx) error {
+	org := store.OrgFromCtx(ctx)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	usage, err := store.GetOrgUsage(storeCtx, org.Name)
+	utils.Check(err)
+
+	org.Usage = usage
+	return ctx.JSON(org)
+}
+
+// HandleUpdateOrg change quota and default auto delete of organization, members is changed by its own endpoint
+func HandleUpdateOrg(ctx *fiber.Ctx) error {
+	policy := new(models.Org)
+	if err := ctx.BodyParser(policy); err != nil {
+		return invalidOrgBody(ctx)
+	}
+
+	if err := store.ValidateOrgPolicy(policy); err != nil {
+		return invalidOrg(ctx, err)
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	org, err := store.UpdateOrg(storeCtx, ctx.Params("org"), func(org *models.Org) error {
+		org.MaxFiles = policy.MaxFiles
+		org.MaxBytes = policy.MaxBytes
+		org.DefaultAutoDeleteIn = policy.DefaultAutoDeleteIn
+		return nil
+	})
+	if err != nil {
+		return orgUpdateError(ctx, err)
+	}
+
+	return ctx.JSON(org)
+}
+
+// HandleDeleteOrg delete organization that has no files anymore, its trash is deleted permanently
+func HandleDeleteOrg(ctx *fiber.Ctx) error {
+	org := store.OrgFromCtx(ctx)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	usage, err := store.GetOrgUsage(storeCtx, org.Name)
+	utils.Check(err)
+
+	if usage.Files > 0 {
+		return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeOrgNotEmpty,
+				Description: fmt.Sprintf("Organization: %s Still Has %d Files, Delete Them First", org.Name, usage.Files),
+			},
+		})
+	}
+
+	if err = store.DeleteOrg(storeCtx, org.Name); err != nil && !errors.Is(err, store.ErrorOrgNotFound) {
+		log.Panic(err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandlePutOrgMember add member to organization or change role of the member
+func HandlePutOrgMember(ctx *fiber.Ctx) error {
+	var (
+		username = ctx.Params("username")
+		role     = ctx.Get(store.HeaderOrgRole)
+	)
+
+	err := store.ValidateUserName(username)
+	if err == nil {
+		err = store.ValidateOrgRole(role)
+	}
+	if err != nil {
+		return invalidOrg(ctx, err)
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	org, err := store.UpdateOrg(storeCtx, ctx.Params("org"), func(org *models.Org) error {
+		org.Members[username] = role
+		return nil
+	})
+	if err != nil {
+		return orgUpdateError(ctx, err)
+	}
+
+	return ctx.JSON(org)
+}
+
+// HandleRemoveOrgMember remove member from organization, only owner can remove other member but any member can leave
+func HandleRemoveOrgMember(ctx *fiber.Ctx) error {
+	var (
+		user     = identity.FromCtx(ctx)
+		org      = store.OrgFromCtx(ctx)
+		username = ctx.Params("username")
+	)
+
+	if username != user.UserName && org.Members[user.UserName] != store.OrgRoleOwner {
+		return ctx.Status(fiber.StatusForbidden).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        "forbidden",
+				Description: "Only owner can remove other member",
+			},
+		})
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	_, err := store.UpdateOrg(storeCtx, org.Name, func(org *models.Org) error {
+		if _, ok := org.Members[username]; !ok {
+			return errOrgMemberNotFound
+		}
+
+		delete(org.Members, username)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errOrgMemberNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeOrgMemberNotFound,
+					Description: fmt.Sprintf("User: %s, Is Not Member of Organization: %s", username, org.Name),
+				},
+			})
+		}
+		return orgUpdateError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+var errOrgMemberNotFound = errors.New("org_member_not_found")
+
+func orgUpdateError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, store.ErrorLastOrgOwner) {
+		return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeOrgOwnerRequired,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	// deleted after it's loaded by middleware
+	if errors.Is(err, store.ErrorOrgNotFound) {
+		return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeOrgNotFound,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	log.Panic(err)
+	return nil
+}
+
+func invalidOrgBody(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeInvalidBody,
+			Description: "Body must be json object of organization, eg: {\"name\": \"my-team\", \"maxBytes\": 1073741824}",
+		},
+	})
+}
+
+func invalidOrg(ctx *fiber.Ctx, err error) error {
+	return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeInvalidOrg,
+			Description: utils.Describe(err.Error()),
+		},
+	})
+}
+
+// orgQuotaExceeded response when file cannot be added to organization because of its quota
+func orgQuotaExceeded(ctx *fiber.Ctx, err error) error {
+	if !errors.Is(err, store.ErrorQuotaExceeded) {
+		log.Panic(err)
+	}
+
+	return ctx.Status(fiber.StatusInsufficientStorage).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeQuotaExceeded,
+			Description: "Organization has reached its maximum files or size",
+		},
+	})
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleOrg(test *testing.T) {
+	app := fiber.New()
+
+	// authenticated by middleware
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals(identity.LocalKey, &identity.Identity{
+			UserName: ctx.Get("test-username"),
+			Guest:    strings.HasPrefix(ctx.Get("test-username"), "tempsyanonym-"),
+		})
+		ctx.Locals(store.LocalOrg, &models.Org{
+			Name:    "test-org",
+			Members: map[string]string{"test-owner": store.OrgRoleOwner, "test-reader": store.OrgRoleReader},
+		})
+
+		return ctx.Next()
+	})
+
+	app.Post("/orgs", HandleCreateOrg)
+	app.Put("/orgs/:org/members/:username", HandlePutOrgMember)
+	app.Delete("/orgs/:org/members/:username", HandleRemoveOrgMember)
+
+	tableTests := []struct {
+		name     string
+		method   string
+		url      string
+		username string
+		body     string
+		role     string
+		status   int
+		errType  string
+	}{
+		{
+			name:     "TestOnGuestCreate",
+			method:   fiber.MethodPost,
+			url:      "/orgs",
+			username: "tempsyanonym-1-test",
+			body:     `{"name": "test-org"}`,
+			status:   fiber.StatusForbidden,
+			errType:  "forbidden",
+		},
+		{
+			name:     "TestOnInvalidBody",
+			method:   fiber.MethodPost,
+			url:      "/orgs",
+			username: "test-owner",
+			body:     `["test-org"]`,
+			status:   fiber.StatusBadRequest,
+			errType:  utils.ErrorTypeInvalidBody,
+		},
+		{
+			name:     "TestOnInvalidName",
+			method:   fiber.MethodPost,
+			url:      "/orgs",
+			username: "test-owner",
+			body:     `{"name": "Test Org"}`,
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidOrg,
+		},
+		{
+			name:     "TestOnInvalidPolicy",
+			method:   fiber.MethodPost,
+			url:      "/orgs",
+			username: "test-owner",
+			body:     `{"name": "test-org", "maxFiles": -1}`,
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidOrg,
+		},
+		{
+			name:     "TestOnInvalidRole",
+			method:   fiber.MethodPut,
+			url:      "/orgs/test-org/members/test-member",
+			username: "test-owner",
+			role:     "admin",
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidOrg,
+		},
+		{
+			name:     "TestOnInvalidMember",
+			method:   fiber.MethodPut,
+			url:      "/orgs/test-org/members/org:other",
+			username: "test-owner",
+			role:     store.OrgRoleReader,
+			status:   fiber.StatusUnprocessableEntity,
+			errType:  utils.ErrorTypeInvalidOrg,
+		},
+		{
+			name:     "TestOnReaderRemoveOther",
+			method:   fiber.MethodDelete,
+			url:      "/orgs/test-org/members/test-owner",
+			username: "test-reader",
+			status:   fiber.StatusForbidden,
+			errType:  "forbidden",
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(table.method, table.url, strings.NewReader(table.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set("test-username", table.username)
+			req.Header.Set(store.HeaderOrgRole, table.role)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, table.status, res.StatusCode)
+			assert.Equal(test, table.errType, apiErr.Error.Kind)
+		})
+	}
+}
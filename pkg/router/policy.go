@@ -0,0 +1,11 @@
+package router
+
+import (
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HandleGetMimePolicy return active content type policy for the user, so client can validate before upload
+func HandleGetMimePolicy(ctx *fiber.Ctx) error {
+	return ctx.JSON(store.MimePolicyFor(store.AccountTierOf(ctx.Params("username"))))
+}
@@ -0,0 +1,61 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetMimePolicy(test *testing.T) {
+	app := fiber.New()
+
+	app.Get("/:username/policy", HandleGetMimePolicy)
+
+	tableTests := []struct {
+		name     string
+		username string
+		tier     store.AccountTier
+	}{
+		{
+			name:     "TestUser",
+			username: "policy-test",
+			tier:     store.AccountTierUser,
+		},
+		{
+			name:     "TestGuest",
+			username: guest.GenerateUsername(),
+			tier:     store.AccountTierGuest,
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/"+table.username+"/policy", nil)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiRes := new(store.MimePolicy)
+			require.NoError(test, json.Unmarshal(body, &apiRes))
+
+			assert.Equal(test, fiber.StatusOK, res.StatusCode)
+			assert.Equal(test, table.tier, apiRes.Tier)
+			assert.NotEmpty(test, apiRes.Allow)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandlePresignUpload validate upload request without the file body,
+// the file is uploaded directly to the storage and must be finalized
+func HandlePresignUpload(ctx *fiber.Ctx) error {
+	fileName, fileMetadata, uploadErr := parseUploadHeader(ctx, nil)
+	if uploadErr != nil {
+		return ctx.Status(uploadErr.status).JSON(&models.ApiError{Error: uploadErr.Error})
+	}
+
+	maxSize := int64(ctx.App().Config().BodyLimit)
+	fileSize, err := strconv.ParseInt(ctx.Get(store.HeaderFileSize), 10, 64)
+	if err != nil || fileSize < 1 || fileSize > maxSize {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidFileSize,
+				Description: fmt.Sprintf("Header: %s, Must Be Between 1 And %d Bytes", store.HeaderFileSize, maxSize),
+			},
+		})
+	}
+
+	// file is never read by the server without encryption, so sha256 for the blob is declared by the client
+	sha256Hex, err := store.DeclaredSha256(store.MapFileHeader(ctx.GetReqHeaders()))
+	if err != nil {
+		status := fiber.StatusUnprocessableEntity
+		if errors.Is(err, store.ErrorSha256Required) {
+			status = fiber.StatusBadRequest
+		}
+
+		return ctx.Status(status).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	filePath := fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+
+	// Check if file already exists
+	if _, err = store.GetObject(storeCtx, filePath); err == nil {
+		return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeFileExists,
+				Description: fmt.Sprintf("File: %s Already Exists", fileName),
+			},
+		})
+	}
+	if !errors.Is(err, storage.ErrObjectNotExist) {
+		log.Panic(err)
+	}
+
+	if err = store.CheckOrgQuota(storeCtx, store.OrgFromCtx(ctx), 1, fileSize); err != nil {
+		return orgQuotaExceeded(ctx, err)
+	}
+
+	// only allow the declared size, the real file size is checked when finalized
+	presigned, err := store.CreatePendingUpload(storeCtx, filePath, fileMetadata, ctx.Get(fiber.HeaderContentType), sha256Hex, fileSize)
+	utils.Check(err)
+
+	return ctx.Status(fiber.StatusCreated).JSON(presigned)
+}
+
+// HandleFinalizeUpload verify the file uploaded by presigned url, then make it available as usual file
+func HandleFinalizeUpload(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	upload, err := store.GetPendingUpload(storeCtx, ctx.Params("id"))
+	if err != nil && !errors.Is(err, store.ErrorUploadNotFound) {
+		log.Panic(err)
+	}
+
+	username := ctx.Params("username")
+	if upload == nil || !strings.HasPrefix(upload.FilePath, username+"/") {
+		return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeUploadNotFound,
+				Description: fmt.Sprintf("Upload: %s, Is Not Found Or Expired", ctx.Params("id")),
+			},
+		})
+	}
+
+	fileName := strings.TrimPrefix(upload.FilePath, username+"/")
+	fileConflict := &models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeFileExists,
+			Description: fmt.Sprintf("File: %s Already Exists", fileName),
+		},
+	}
+
+	// file can be uploaded by another request, while waiting to be finalized
+	if _, err = store.GetObject(storeCtx, upload.FilePath); err == nil {
+		store.DiscardPendingUpload(storeCtx, upload)
+		return ctx.Status(fiber.StatusConflict).JSON(fileConflict)
+	}
+	if !errors.Is(err, storage.ErrObjectNotExist) {
+		log.Panic(err)
+	}
+
+	mimePolicy := store.MimePolicyFor(store.AccountTierOf(username))
+	if err = store.FinalizeUpload(storeCtx, upload, mimePolicy); err != nil {
+		switch {
+		case errors.Is(err, store.ErrorUploadNotCompleted):
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeUploadIncomplete,
+					Description: utils.Describe(err.Error()),
+				},
+			})
+		case errors.Is(err, store.ErrorUploadInvalidSize):
+			return ctx.Status(fiber.StatusRequestEntityTooLarge).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeInvalidFileSize,
+					Description: utils.Describe(err.Error()),
+				},
+			})
+		case errors.Is(err, store.ErrorChecksumMismatch):
+			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeChecksumMismatch,
+					Description: "Checksum of uploaded file does not match with checksum header",
+				},
+			})
+		case errors.Is(err, store.ErrorContentMismatch):
+			return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeContentMismatch,
+					Description: utils.Describe(err.Error()) + ": " + upload.DeclaredType,
+				},
+			})
+		case store.IsPreconditionFailed(err):
+			store.DiscardPendingUpload(storeCtx, upload)
+			return ctx.Status(fiber.StatusConflict).JSON(fileConflict)
+		}
+		log.Panic(err)
+	}
+
+	dataFile, err := store.GetObject(storeCtx, upload.FilePath)
+	utils.Check(err)
+
+	store.Format(dataFile)
+	return ctx.Status(fiber.StatusCreated).JSON(&dataFile)
+}
@@ -0,0 +1,148 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlePresignUpload(test *testing.T) {
+	const username = "presign-test"
+
+	app := fiber.New(fiber.Config{BodyLimit: 1024})
+	app.Post("/api/files/:username/presign", HandlePresignUpload)
+	app.Post("/api/files/:username/presign/:id", HandleFinalizeUpload)
+
+	tableErrs := []struct {
+		headers    map[string]string
+		name       string
+		path       string
+		errType    string
+		statusCode int
+	}{
+		{
+			name: "TestOnInvalidFileName",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "example",
+				store.HeaderFileSize:          "10",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidFileName,
+			statusCode: fiber.StatusBadRequest,
+		},
+		{
+			name: "TestOnInvalidContentType",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "1.json",
+				store.HeaderFileSize:          "10",
+				fiber.HeaderContentType:       fiber.MIMEOctetStream,
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeUnsupportedType,
+			statusCode: fiber.StatusUnsupportedMediaType,
+		},
+		{
+			name: "TestOnInvalidHeaderFile",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "test.txt",
+				store.HeaderFileSize:          "10",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidHeaderFile,
+			statusCode: fiber.StatusUnprocessableEntity,
+		},
+		{
+			name: "TestOnMissingSha256",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "test.txt",
+				store.HeaderFileSize:          "10",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidHeaderFile,
+			statusCode: fiber.StatusBadRequest,
+		},
+		{
+			name: "TestOnMissingFileSize",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "test.txt",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidFileSize,
+			statusCode: fiber.StatusBadRequest,
+		},
+		{
+			name: "TestOnFileSizeExceedLimit",
+			path: "/api/files/" + username + "/presign",
+			headers: map[string]string{
+				store.HeaderFileName:          "test.txt",
+				store.HeaderFileSize:          "1025",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidFileSize,
+			statusCode: fiber.StatusBadRequest,
+		},
+		{
+			name:       "TestOnFinalizeUploadNotFound",
+			path:       "/api/files/" + username + "/presign/invalid.id",
+			errType:    utils.ErrorTypeUploadNotFound,
+			statusCode: fiber.StatusNotFound,
+		},
+	}
+
+	for _, tableE := range tableErrs {
+		test.Run(tableE.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, tableE.path, nil)
+			for key, value := range tableE.headers {
+				req.Header.Set(key, value)
+			}
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+			require.NotEmpty(test, res)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+			require.NotEmpty(test, body)
+
+			apiRes := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiRes))
+
+			assert.Equal(test, tableE.statusCode, res.StatusCode)
+			assert.Equal(test, tableE.errType, apiRes.Error.Kind)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleCreateShare create share link of file, it's independent of file is public or not
+func HandleCreateShare(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileNotFound,
+					Description: fmt.Sprintf("File: %s, Is Not Found", fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	record, err := store.UnmarshalShareHeader(store.MapFileHeader(ctx.GetReqHeaders()), fileData)
+	if err != nil {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderShare,
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	utils.Check(store.CreateShare(storeCtx, record))
+
+	return ctx.Status(fiber.StatusCreated).JSON(record.Share())
+}
+
+func HandleListShares(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileNotFound,
+					Description: fmt.Sprintf("File: %s, Is Not Found", fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	records, err := store.ListShares(storeCtx, filePath)
+	utils.Check(err)
+
+	shares := make([]*models.Share, 0, len(records))
+	for _, record := range records {
+		// share of previous file with the same name
+		if record.FileId != fileData.FileId {
+			continue
+		}
+
+		shares = append(shares, record.Share())
+	}
+
+	return ctx.JSON(&shares)
+}
+
+func HandleRevokeShare(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	record, err := store.GetShare(storeCtx, ctx.Params("id"))
+	if err == nil && record.FilePath != filePath {
+		err = store.ErrorShareNotFound
+	}
+
+	if err == nil {
+		err = store.RevokeShare(storeCtx, record)
+	}
+
+	if err != nil {
+		if errors.Is(err, store.ErrorShareNotFound) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeShareNotFound,
+					Description: fmt.Sprintf("Share: %s of File: %s, Is Not Found Or Expired", ctx.Params("id"), fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleGetShare resolve share link through the server, so it can be revoked at any time
+func HandleGetShare(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	id := ctx.Params("id")
+
+	record, fileData, err := getSharedFile(storeCtx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrorShareNotFound) {
+			return shareNotFound(ctx, id)
+		}
+		log.Panic(err)
+	}
+
+	// revoked or expired share must not be served from cache
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	if errType := unlockPublicFile(ctx, "share:"+id, record.PasswordHash); errType != "" {
+		return lockedPublicFile(ctx, errType)
+	}
+
+	// HEAD request does not download the file
+	if ctx.Method() != fiber.MethodHead {
+		if err = store.ConsumeShare(storeCtx, id); err != nil {
+			if errors.Is(err, store.ErrorShareNotFound) {
+				return shareNotFound(ctx, id)
+			}
+			log.Panic(err)
+		}
+	}
+
+	fileByte, err := store.ReadObject(storeCtx, record.FilePath)
+	utils.Check(err)
+
+	store.SetPublicHeaders(ctx, record.Share().FileName, fileData.MimeType)
+	ctx.Set(fiber.HeaderContentLength, fmt.Sprintf("%d", len(fileByte)))
+	store.SetDigestHeaders(ctx, fileByte)
+	if fileData.E2eHeader != "" {
+		ctx.Set(store.HeaderE2eHeader, fileData.E2eHeader)
+	}
+
+	return ctx.Send(fileByte)
+}
+
+// HandleUnlockShare unlock password protected share link with form post, then redirect to the share link
+func HandleUnlockShare(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	id := ctx.Params("id")
+
+	record, _, err := getSharedFile(storeCtx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrorShareNotFound) {
+			return shareNotFound(ctx, id)
+		}
+		log.Panic(err)
+	}
+
+	if errType := unlockPublicFile(ctx, "share:"+id, record.PasswordHash); errType != "" {
+		return lockedPublicFile(ctx, errType)
+	}
+
+	return ctx.Redirect(ctx.Path(), fiber.StatusSeeOther)
+}
+
+// getSharedFile return share and the file, share of deleted file is revoked
+func getSharedFile(ctx context.Context, id string) (*store.ShareRecord, *models.DataFile, error) {
+	record, err := store.GetShare(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileData, err := store.GetObject(ctx, record.FilePath)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil, err
+	}
+
+	// file is deleted, or deleted and uploaded again with the same name
+	if err != nil || fileData.FileId != record.FileId {
+		utils.LogErr(store.RevokeShare(ctx, record))
+		return nil, nil, store.ErrorShareNotFound
+	}
+
+	return record, fileData, nil
+}
+
+func shareNotFound(ctx *fiber.Ctx, id string) error {
+	return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeShareNotFound,
+			Description: fmt.Sprintf("Share: %s, Is Not Found Or Expired", id),
+		},
+	})
+}
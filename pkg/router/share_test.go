@@ -0,0 +1,179 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleShare(test *testing.T) {
+	const username = "test-share"
+
+	var (
+		app      = fiber.New()
+		fileName = strings.ToLower(test.Name()) + ".txt"
+		filePath = username + "/" + fileName
+		fileByte = []byte(test.Name())
+	)
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+
+	app.Post("/:username/:filename/shares", HandleCreateShare)
+	app.Get("/:username/:filename/shares", HandleListShares)
+	app.Delete("/:username/:filename/shares/:id", HandleRevokeShare)
+	app.Get("/s/:id", HandleGetShare)
+
+	require.NoError(test, store.UploadObject(storeCtx, filePath, fileByte, &models.DataFile{
+		AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 10, // 10 seconds
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+	}))
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.LogErr(store.DeleteObject(storeCtx, filePath))
+	})
+
+	share := new(models.Share)
+
+	test.Run("TestOkCreate", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodPost, "/"+username+"/"+fileName+"/shares", nil)
+		req.Header.Set(store.HeaderMaxDownloads, "1")
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+		require.NoError(test, json.Unmarshal(body, share))
+
+		assert.Equal(test, fiber.StatusCreated, res.StatusCode)
+		assert.NotEmpty(test, share.Id)
+		assert.Contains(test, share.Url, "/s/"+share.Id)
+		assert.NotContains(test, share.Url, username)
+		assert.Equal(test, fileName, share.FileName)
+	})
+
+	test.Run("TestOkList", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/"+username+"/"+fileName+"/shares", nil)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+
+		shares := make([]*models.Share, 0)
+		require.NoError(test, json.Unmarshal(body, &shares))
+
+		assert.Equal(test, fiber.StatusOK, res.StatusCode)
+		require.Len(test, shares, 1)
+		assert.Equal(test, share.Id, shares[0].Id)
+	})
+
+	test.Run("TestOkResolveOnce", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/s/"+share.Id, nil)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+
+		assert.Equal(test, fiber.StatusOK, res.StatusCode)
+		assert.Equal(test, fileByte, body)
+		assert.Equal(test, "no-store", res.Header.Get(fiber.HeaderCacheControl))
+
+		req = httptest.NewRequest(fiber.MethodGet, "/s/"+share.Id, nil)
+
+		res, err = app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusNotFound, res.StatusCode)
+	})
+
+	test.Run("TestOkRevoke", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodPost, "/"+username+"/"+fileName+"/shares", nil)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		revoked := new(models.Share)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+		require.NoError(test, json.Unmarshal(body, revoked))
+
+		req = httptest.NewRequest(fiber.MethodDelete, "/"+username+"/"+fileName+"/shares/"+revoked.Id, nil)
+
+		res, err = app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusNoContent, res.StatusCode)
+
+		req = httptest.NewRequest(fiber.MethodGet, "/s/"+revoked.Id, nil)
+
+		res, err = app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusNotFound, res.StatusCode)
+	})
+
+	test.Run("TestOnInvalidId", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/s/not-valid!", nil)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+
+		apiErr := new(models.ApiError)
+		require.NoError(test, json.Unmarshal(body, &apiErr))
+
+		assert.Equal(test, fiber.StatusNotFound, res.StatusCode)
+		assert.Equal(test, utils.ErrorTypeShareNotFound, apiErr.Error.Kind)
+	})
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleListTrash list deleted files that can be restored
+func HandleListTrash(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	entries, err := store.ListTrash(storeCtx, ctx.Params("username"))
+	utils.Check(err)
+
+	return ctx.JSON(&entries)
+}
+
+// HandleRestoreTrash move deleted file back, with the same name
+func HandleRestoreTrash(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	filePath, err := store.RestoreTrash(storeCtx, ctx.Params("username"), ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, store.ErrorTrashNotFound) {
+			return trashNotFound(ctx)
+		}
+
+		if store.IsPreconditionFailed(err) {
+			return ctx.Status(fiber.StatusConflict).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileExists,
+					Description: "File with the same name already exists, please delete or rename it first",
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	utils.Check(err)
+
+	store.Format(fileData)
+	return ctx.JSON(&fileData)
+}
+
+// HandlePurgeTrash delete file in trash permanently
+func HandlePurgeTrash(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if err := store.PurgeTrash(storeCtx, ctx.Params("username"), ctx.Params("id")); err != nil {
+		if errors.Is(err, store.ErrorTrashNotFound) {
+			return trashNotFound(ctx)
+		}
+		log.Panic(err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleEmptyTrash delete all files in trash permanently
+func HandleEmptyTrash(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	utils.Check(store.SweepTrash(storeCtx, ctx.Params("username"), true))
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func trashNotFound(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeTrashNotFound,
+			Description: fmt.Sprintf("Trash: %s, Is Not Found", ctx.Params("id")),
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTrash(test *testing.T) {
+	app := fiber.New()
+	app.Post("/api/files/:username/trash/:id/restore", HandleRestoreTrash)
+	app.Delete("/api/files/:username/trash/:id", HandlePurgeTrash)
+
+	tableErrs := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{
+			name:   "TestOnRestoreTrashNotFound",
+			method: fiber.MethodPost,
+			path:   "/api/files/trash-test/trash/invalid/restore",
+		},
+		{
+			name:   "TestOnPurgeTrashNotFound",
+			method: fiber.MethodDelete,
+			path:   "/api/files/trash-test/trash/invalid",
+		},
+	}
+
+	for _, tableE := range tableErrs {
+		test.Run(tableE.name, func(test *testing.T) {
+			res, err := app.Test(httptest.NewRequest(tableE.method, tableE.path, nil), 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiRes := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiRes))
+
+			assert.Equal(test, fiber.StatusNotFound, res.StatusCode)
+			assert.Equal(test, utils.ErrorTypeTrashNotFound, apiRes.Error.Kind)
+		})
+	}
+}
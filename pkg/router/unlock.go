@@ -0,0 +1,117 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleUnlockPublicFile unlock password protected public file with form post,
+// then redirect to the file with unlock cookie
+func HandleUnlockPublicFile(ctx *fiber.Ctx) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileNotPublic,
+					Description: fmt.Sprintf("File: %s, Is Not Found Or Not Public", fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+	if !fileData.IsPublic {
+		return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeFileNotPublic,
+				Description: fmt.Sprintf("File: %s, Is Not Found Or Not Public", fileName),
+			},
+		})
+	}
+
+	if errType := unlockPublicFile(ctx, filePath, fileData.PasswordHash); errType != "" {
+		return lockedPublicFile(ctx, errType)
+	}
+
+	return ctx.Redirect(ctx.Path(), fiber.StatusSeeOther)
+}
+
+// unlockPublicFile check password of protected file from unlock cookie, header or form,
+// unlockKey is file path or share id. Return error type when file is still locked
+func unlockPublicFile(ctx *fiber.Ctx, unlockKey, passwordHash string) string {
+	if passwordHash == "" {
+		return ""
+	}
+
+	// response of protected file must not be cached, it's also skipped by cache middleware
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	if store.VerifyUnlockToken(ctx.Cookies(store.UnlockCookieName), unlockKey, passwordHash) {
+		return ""
+	}
+
+	password := ctx.Get(store.HeaderPassword)
+	if password == "" && ctx.Method() == fiber.MethodPost {
+		// only from body, password in query string can be leaked to logs
+		password = string(ctx.Request().PostArgs().Peek("password"))
+	}
+
+	if password == "" {
+		return utils.ErrorTypePasswordRequired
+	}
+
+	if !store.VerifyPassword(passwordHash, password) {
+		ctx.Locals(store.LocalInvalidPassword, true)
+		return utils.ErrorTypeInvalidPassword
+	}
+
+	expires := time.Now().Add(store.UnlockCookieExpires)
+	ctx.Cookie(&fiber.Cookie{
+		Name:     store.UnlockCookieName,
+		Value:    store.SignUnlockToken(unlockKey, passwordHash, expires),
+		Path:     ctx.Path(), // only for this file or share
+		Expires:  expires,
+		Secure:   os.Getenv("APP_ENV") == "production",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return ""
+}
+
+// lockedPublicFile response with unlock form for browser, status must be error,
+// so the failed attempt is counted by rate limiter
+func lockedPublicFile(ctx *fiber.Ctx, errType string) error {
+	ctx.Status(fiber.StatusUnauthorized)
+
+	if strings.Contains(ctx.Get(fiber.HeaderAccept), fiber.MIMETextHTML) {
+		return ctx.SendFile(path.Join("assets", "unlock.html"))
+	}
+
+	return ctx.JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        errType,
+			Description: utils.Describe(errType),
+		},
+	})
+}
@@ -0,0 +1,152 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnlockPublicFile(test *testing.T) {
+	passwordHash, err := store.HashPassword("secret-password")
+	require.NoError(test, err)
+
+	app := fiber.New()
+	app.Add(fiber.MethodGet, "/:username/:filename", unlockTestHandler(passwordHash))
+	app.Add(fiber.MethodPost, "/:username/:filename", unlockTestHandler(passwordHash))
+
+	test.Run("TestOkHeader", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/test/secret.txt", nil)
+		req.Header.Set(store.HeaderPassword, "secret-password")
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusOK, res.StatusCode)
+		assert.Equal(test, "no-store", res.Header.Get(fiber.HeaderCacheControl))
+
+		// unlock cookie is valid for the same file
+		cookies := res.Cookies()
+		require.Len(test, cookies, 1)
+		assert.Equal(test, store.UnlockCookieName, cookies[0].Name)
+		assert.Equal(test, "/test/secret.txt", cookies[0].Path)
+		assert.True(test, cookies[0].HttpOnly)
+
+		req = httptest.NewRequest(fiber.MethodGet, "/test/secret.txt", nil)
+		req.AddCookie(cookies[0])
+
+		res, err = app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusOK, res.StatusCode)
+
+		req = httptest.NewRequest(fiber.MethodGet, "/test/other.txt", nil)
+		req.AddCookie(cookies[0])
+
+		res, err = app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusUnauthorized, res.StatusCode)
+	})
+
+	test.Run("TestOkForm", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodPost, "/test/secret.txt", strings.NewReader(url.Values{"password": {"secret-password"}}.Encode()))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		assert.Equal(test, fiber.StatusOK, res.StatusCode)
+		assert.NotEmpty(test, res.Cookies())
+	})
+
+	tableErrs := []struct {
+		name     string
+		method   string
+		password string
+		target   string
+		errType  string
+	}{
+		{
+			name:    "TestOnPasswordRequired",
+			method:  fiber.MethodGet,
+			target:  "/test/secret.txt",
+			errType: utils.ErrorTypePasswordRequired,
+		},
+		{
+			name:     "TestOnInvalidPassword",
+			method:   fiber.MethodGet,
+			password: "wrong-password",
+			target:   "/test/secret.txt",
+			errType:  utils.ErrorTypeInvalidPassword,
+		},
+		{
+			name:    "TestOnPasswordInQueryString",
+			method:  fiber.MethodPost,
+			target:  "/test/secret.txt?password=secret-password",
+			errType: utils.ErrorTypePasswordRequired,
+		},
+	}
+
+	for _, tableE := range tableErrs {
+		test.Run(tableE.name, func(test *testing.T) {
+			req := httptest.NewRequest(tableE.method, tableE.target, nil)
+			if tableE.password != "" {
+				req.Header.Set(store.HeaderPassword, tableE.password)
+			}
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, fiber.StatusUnauthorized, res.StatusCode)
+			assert.Equal(test, tableE.errType, apiErr.Error.Kind)
+			assert.Empty(test, res.Cookies())
+		})
+	}
+}
+
+func unlockTestHandler(passwordHash string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		filePath := ctx.Params("username") + "/" + ctx.Params("filename")
+		if errType := unlockPublicFile(ctx, filePath, passwordHash); errType != "" {
+			return lockedPublicFile(ctx, errType)
+		}
+
+		return ctx.SendString("unlocked")
+	}
+}
@@ -17,7 +17,7 @@ import (
 // HandleUpdateFile Updates single file by name
 func HandleUpdateFile(ctx *fiber.Ctx) error {
 	var (
-		fileName = ctx.Params("filename")
+		fileName = store.DecodeFileName(ctx.Params("filename"))
 		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
 	)
 
@@ -58,8 +58,33 @@ func HandleUpdateFile(ctx *fiber.Ctx) error {
 
 	fileHeader := store.MapFileHeader(ctx.GetReqHeaders())
 
+	if checksumErr := verifyChecksum(fileHeader, ctx.Body()); checksumErr != nil {
+		return ctx.Status(checksumErr.status).JSON(&models.ApiError{Error: checksumErr.Error})
+	}
+
 	fileMetadata := new(models.DataFile)
-	fileMetadata.MimeType = fileHeader.Get(fiber.HeaderContentType)
+
+	// end-to-end encrypted file is cipher text, so it cannot be checked by policy or magic bytes
+	if fileHeader.Get(store.HeaderE2eHeader) != "" {
+		fileMetadata.MimeType = fiber.MIMEOctetStream
+	} else if fileMetadata.MimeType, err = store.VerifyContentType(store.MimePolicyFor(store.AccountTierOf(ctx.Params("username"))), fileHeader.Get(fiber.HeaderContentType), ctx.Body()); err != nil {
+		return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeContentMismatch,
+				Description: utils.Describe(err.Error()) + ": " + fileHeader.Get(fiber.HeaderContentType),
+			},
+		})
+	}
+
+	// detected type can be different when policy correct it
+	if !strings.Contains(file.MimeType, fileMetadata.MimeType) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeMismatchType,
+				Description: "Please use the same content type as the original file",
+			},
+		})
+	}
 
 	if err = store.UnmarshalMetadata(fileHeader, fileMetadata); err != nil {
 		log.Error("Error Unmarshal File Metadata: " + err.Error())
@@ -67,7 +92,40 @@ func HandleUpdateFile(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
 			Error: &models.Error{
 				Kind:        utils.ErrorTypeInvalidHeaderFile,
-				Description: strings.Join(strings.Split(err.Error(), "_"), " "),
+				Description: utils.Describe(err.Error()),
+			},
+		})
+	}
+
+	// history is kept as before when it's not changed, since 0 delete all versions
+	if fileHeader.Get(store.HeaderMaxVersions) == "" {
+		fileMetadata.MaxVersions = file.MaxVersions
+	}
+
+	// cannot switch between end-to-end encrypted file and plain file
+	if (file.E2eHeader == "") != (fileMetadata.E2eHeader == "") {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: "End-to-end encrypted file must be updated with e2e header, and plain file cannot be updated with it",
+			},
+		})
+	}
+
+	// grantee only replace the content, who can access the file and how long it's kept is decided by the owner.
+	// password is never stored, only the salted hash
+	if store.GrantFromCtx(ctx) != nil {
+		fileMetadata.IsPublic = file.IsPublic
+		fileMetadata.PasswordHash = file.PasswordHash
+		fileMetadata.MaxDownloads = file.MaxDownloads
+		fileMetadata.AutoDeleteAt = file.AutoDeleteAt
+		fileMetadata.PrivateUrlExpires = file.PrivateUrlExpires
+		fileMetadata.MaxVersions = file.MaxVersions
+	} else if fileMetadata.PasswordHash, err = store.HashPassword(fileHeader.Get(store.HeaderPassword)); err != nil {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
 			},
 		})
 	}
@@ -78,15 +136,20 @@ func HandleUpdateFile(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
 			Error: &models.Error{
 				Kind:        utils.ErrorTypeInvalidHeaderFile,
-				Description: strings.Join(strings.Split(err.Error(), "_"), " "),
+				Description: utils.Describe(err.Error()),
 			},
 		})
 	}
 
 	fileMetadata.Name = fileName // Bypass file name, for preventing file name change
+	fileMetadata.FileId = file.FileId
+
+	// only the difference of size is added, since the content is replaced
+	if err = store.CheckOrgQuota(storeCtx, store.OrgFromCtx(ctx), 0, int64(len(ctx.Body()))-file.Size); err != nil {
+		return orgQuotaExceeded(ctx, err)
+	}
 
-	utils.Check(store.DeleteObject(storeCtx, filePath))
-	utils.Check(store.UploadObject(storeCtx, filePath, ctx.Body(), fileMetadata))
+	utils.Check(store.ReplaceObject(storeCtx, filePath, ctx.Body(), fileMetadata))
 
 	fileData, err := store.GetObject(storeCtx, filePath)
 	utils.Check(err)
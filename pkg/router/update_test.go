@@ -101,6 +101,41 @@ func TestHandleUpdateFile(test *testing.T) {
 		assert.NotContains(test, apiRes.Url, username+"/public/")
 	})
 
+	test.Run("TestOkGranteeKeepSettings", func(test *testing.T) {
+		grantApp := fiber.New()
+		grantApp.Put("/api/files/:username/:filename", func(ctx *fiber.Ctx) error {
+			ctx.Locals(store.LocalGrant, &store.GrantRecord{FilePath: filePath, Grantee: "grantee", Role: store.GrantRoleWrite})
+			return ctx.Next()
+		}, HandleUpdateFile)
+
+		file, err := store.GetObject(storeCtx, filePath)
+		require.NoError(test, err)
+
+		req := httptest.NewRequest(fiber.MethodPut, "/api/files/"+filePath, bytes.NewReader(fileByte))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		req.Header.Set(store.HeaderIsPublic, "-1")
+		req.Header.Set(store.HeaderAutoDeleteAt, fmt.Sprintf("%d", time.Now().Add(5*time.Minute).UnixMilli()))
+		req.Header.Set(store.HeaderPrivateUrlExpires, "60") // 1 minute
+
+		res, err := grantApp.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+
+		apiRes := new(models.DataFile)
+		require.NoError(test, json.Unmarshal(body, &apiRes))
+
+		require.Equal(test, fiber.StatusOK, res.StatusCode)
+		assert.Equal(test, file.IsPublic, apiRes.IsPublic)
+		assert.Equal(test, file.AutoDeleteAt, apiRes.AutoDeleteAt)
+		assert.Equal(test, file.PrivateUrlExpires, apiRes.PrivateUrlExpires)
+	})
+
 	tableErrs := []struct {
 		headers    map[string]string
 		fileName   string
@@ -184,3 +219,97 @@ func TestHandleUpdateFile(test *testing.T) {
 		})
 	}
 }
+
+func TestHandleUpdateFileKeepVersions(test *testing.T) {
+	const username = "update-test"
+
+	var (
+		app      = fiber.New()
+		filePath = fmt.Sprintf("%s/%s.txt", username, strings.ToLower(test.Name()))
+	)
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.Check(store.DeleteObject(storeCtx, filePath))
+	})
+
+	app.Put("/api/files/:username/:filename", HandleUpdateFile)
+
+	require.NoError(test, store.UploadObject(storeCtx, filePath, []byte("v1"), &models.DataFile{
+		AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 10, // 10 seconds
+		MaxVersions:       -1,
+		MimeType:          fiber.MIMETextPlainCharsetUTF8,
+	}))
+
+	// update without max versions header
+	for _, content := range []string{"v2", "v3"} {
+		req := httptest.NewRequest(fiber.MethodPut, "/api/files/"+filePath, strings.NewReader(content))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		req.Header.Set(store.HeaderAutoDeleteAt, fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()))
+		req.Header.Set(store.HeaderPrivateUrlExpires, "10") // 10 seconds
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+		utils.LogErr(res.Body.Close())
+		require.Equal(test, fiber.StatusOK, res.StatusCode)
+	}
+
+	versions, err := store.ListVersions(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Len(test, versions, 2)
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	require.NoError(test, err)
+	assert.Equal(test, -1, fileData.MaxVersions)
+}
+
+func TestHandleUpdateE2eFile(test *testing.T) {
+	const username = "update-test"
+
+	var (
+		app      = fiber.New()
+		filePath = fmt.Sprintf("%s/%s.bin", username, strings.ToLower(test.Name()))
+	)
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+
+	test.Cleanup(func() {
+		defer cancel()
+
+		utils.Check(store.DeleteObject(storeCtx, filePath))
+	})
+
+	app.Put("/api/files/:username/:filename", HandleUpdateFile)
+
+	require.NoError(test, store.UploadObject(storeCtx, filePath, []byte("cipher-v1"), &models.DataFile{
+		AutoDeleteAt:      time.Now().Add(1 * time.Minute).UnixMilli(),
+		PrivateUrlExpires: 10, // 10 seconds
+		MimeType:          fiber.MIMEOctetStream,
+		E2eHeader:         "v1.q1XHz2aFkc0l0bVd",
+	}))
+
+	// cipher text can look like any type, it's never sniffed
+	req := httptest.NewRequest(fiber.MethodPut, "/api/files/"+filePath, strings.NewReader("\x89PNG\r\n\x1a\ncipher-v2"))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	req.Header.Set(store.HeaderE2eHeader, "v1.q1XHz2aFkc0l0bVd")
+	req.Header.Set(store.HeaderAutoDeleteAt, fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()))
+	req.Header.Set(store.HeaderPrivateUrlExpires, "10") // 10 seconds
+
+	res, err := app.Test(req, 1500*10) // 15 seconds
+	require.NoError(test, err)
+
+	test.Cleanup(func() {
+		utils.LogErr(res.Body.Close())
+	})
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(test, err)
+
+	apiRes := new(models.DataFile)
+	require.NoError(test, json.Unmarshal(body, &apiRes))
+
+	require.Equal(test, fiber.StatusOK, res.StatusCode)
+	assert.Equal(test, fiber.MIMEOctetStream, apiRes.MimeType)
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
@@ -14,15 +12,9 @@ import (
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
-	"golang.org/x/exp/slices"
 )
 
 func HandleUploadFile(ctx *fiber.Ctx) error {
-	var (
-		fileName = ctx.Get(store.HeaderFileName)
-		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
-	)
-
 	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
 	defer cancel()
 
@@ -35,58 +27,23 @@ func HandleUploadFile(ctx *fiber.Ctx) error {
 		})
 	}
 
-	match, err := regexp.MatchString(`^[a-zA-Z0-9_-]+\.+[a-zA-Z0-9_-]+$`, fileName)
-	utils.Check(err)
+	fileName, fileMetadata, uploadErr := parseUploadHeader(ctx, ctx.Body())
+	if uploadErr != nil {
+		return ctx.Status(uploadErr.status).JSON(&models.ApiError{Error: uploadErr.Error})
+	}
 
-	if !match {
-		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
-			Error: &models.Error{
-				Kind:        utils.ErrorTypeInvalidFileName,
-				Description: "File name must be alphanumeric lowercase or uppercase split by underscore, or dash and contain extension separated by dot",
-			},
-		})
+	if ctx.QueryBool("extract") {
+		return extractUpload(ctx, fileMetadata)
 	}
 
+	filePath := fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+
 	// Check if file already exists
 	dataFile, err := store.GetObject(storeCtx, filePath)
 	if err != nil {
 		if errors.Is(err, storage.ErrObjectNotExist) {
-			var (
-				fileHeader  = store.MapFileHeader(ctx.GetReqHeaders())
-				contentType = fileHeader.Get(fiber.HeaderContentType)
-			)
-			fileMetadata := new(models.DataFile)
-			if !slices.Contains(store.AcceptedContentType, contentType) {
-				return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(&models.ApiError{
-					Error: &models.Error{
-						Kind:        utils.ErrorTypeUnsupportedType,
-						Description: "Unsupported Content-Type: " + contentType,
-					},
-				})
-			}
-
-			fileMetadata.MimeType = contentType
-
-			if err = store.UnmarshalMetadata(fileHeader, fileMetadata); err != nil {
-				log.Error("Error Unmarshal File Metadata: " + err.Error())
-
-				return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
-					Error: &models.Error{
-						Kind:        utils.ErrorTypeInvalidHeaderFile,
-						Description: strings.Join(strings.Split(err.Error(), "_"), " "),
-					},
-				})
-			}
-
-			if err = validateExpiry(fileMetadata.PrivateUrlExpires, fileMetadata.AutoDeleteAt); err != nil {
-				log.Error("Error Validate Expiry: " + err.Error())
-
-				return ctx.Status(fiber.StatusUnprocessableEntity).JSON(&models.ApiError{
-					Error: &models.Error{
-						Kind:        utils.ErrorTypeInvalidHeaderFile,
-						Description: strings.Join(strings.Split(err.Error(), "_"), " "),
-					},
-				})
+			if err = store.CheckOrgQuota(storeCtx, store.OrgFromCtx(ctx), 1, int64(len(ctx.Body()))); err != nil {
+				return orgQuotaExceeded(ctx, err)
 			}
 
 			utils.Check(store.UploadObject(storeCtx, filePath, ctx.Body(), fileMetadata))
@@ -109,6 +66,146 @@ func HandleUploadFile(ctx *fiber.Ctx) error {
 	})
 }
 
+// uploadError validation error of upload request, response with status
+type uploadError struct {
+	*models.Error
+	status int
+}
+
+// parseUploadHeader validate file name, content type and file metadata of upload request,
+// file content is verified only when fileByte is not nil, since presigned upload is verified after uploaded
+func parseUploadHeader(ctx *fiber.Ctx, fileByte []byte) (string, *models.DataFile, *uploadError) {
+	fileName, err := store.ValidateFileName(store.DecodeFileName(ctx.Get(store.HeaderFileName)))
+	if err != nil {
+		return "", nil, &uploadError{
+			status: fiber.StatusBadRequest,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidFileName,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	var (
+		fileHeader  = store.MapFileHeader(ctx.GetReqHeaders())
+		contentType = fileHeader.Get(fiber.HeaderContentType)
+		mimePolicy  = store.MimePolicyFor(store.AccountTierOf(ctx.Params("username")))
+	)
+	fileMetadata := &models.DataFile{MimeType: contentType}
+
+	// default auto delete policy of organization
+	if org := store.OrgFromCtx(ctx); org != nil && org.DefaultAutoDeleteIn > 0 && fileHeader.Get(store.HeaderAutoDeleteAt) == "" {
+		fileHeader[store.HeaderAutoDeleteAt] = fmt.Sprintf("%d", time.Now().UnixMilli()+org.DefaultAutoDeleteIn)
+	}
+
+	if fileByte != nil {
+		if checksumErr := verifyChecksum(fileHeader, fileByte); checksumErr != nil {
+			return "", nil, checksumErr
+		}
+	}
+
+	// end-to-end encrypted file is cipher text, so it cannot be checked by policy or magic bytes
+	if fileHeader.Get(store.HeaderE2eHeader) != "" {
+		if store.MediaType(contentType) != fiber.MIMEOctetStream {
+			return "", nil, &uploadError{
+				status: fiber.StatusUnsupportedMediaType,
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeUnsupportedType,
+					Description: "End-to-end encrypted file must use Content-Type: " + fiber.MIMEOctetStream,
+				},
+			}
+		}
+
+		fileMetadata.MimeType = fiber.MIMEOctetStream
+	} else {
+		if !mimePolicy.Allows(contentType) {
+			return "", nil, &uploadError{
+				status: fiber.StatusUnsupportedMediaType,
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeUnsupportedType,
+					Description: "Unsupported Content-Type: " + contentType,
+				},
+			}
+		}
+
+		if fileByte != nil {
+			fileMetadata.MimeType, err = store.VerifyContentType(mimePolicy, contentType, fileByte)
+			if err != nil {
+				return "", nil, &uploadError{
+					status: fiber.StatusUnsupportedMediaType,
+					Error: &models.Error{
+						Kind:        utils.ErrorTypeContentMismatch,
+						Description: utils.Describe(err.Error()) + ": " + contentType,
+					},
+				}
+			}
+		}
+	}
+
+	if err = store.UnmarshalMetadata(fileHeader, fileMetadata); err != nil {
+		log.Error("Error Unmarshal File Metadata: " + err.Error())
+
+		return "", nil, &uploadError{
+			status: fiber.StatusUnprocessableEntity,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	// password is never stored, only the salted hash
+	if fileMetadata.PasswordHash, err = store.HashPassword(fileHeader.Get(store.HeaderPassword)); err != nil {
+		return "", nil, &uploadError{
+			status: fiber.StatusUnprocessableEntity,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	if err = validateExpiry(fileMetadata.PrivateUrlExpires, fileMetadata.AutoDeleteAt); err != nil {
+		log.Error("Error Validate Expiry: " + err.Error())
+
+		return "", nil, &uploadError{
+			status: fiber.StatusUnprocessableEntity,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidHeaderFile,
+				Description: utils.Describe(err.Error()),
+			},
+		}
+	}
+
+	return fileName, fileMetadata, nil
+}
+
+// verifyChecksum verify file content with checksum headers sent by client
+func verifyChecksum(fileHeader store.FileHeader, fileByte []byte) *uploadError {
+	err := store.VerifyChecksum(fileHeader, fileByte)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, store.ErrorChecksumMismatch) {
+		return &uploadError{
+			status: fiber.StatusBadRequest,
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeChecksumMismatch,
+				Description: "Checksum of uploaded file does not match with checksum header",
+			},
+		}
+	}
+
+	return &uploadError{
+		status: fiber.StatusUnprocessableEntity,
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeInvalidHeaderFile,
+			Description: utils.Describe(err.Error()),
+		},
+	}
+}
+
 func validateExpiry(urlExp uint, autoDel int64) error {
 	if time.Now().Add(time.Duration(urlExp)*time.Second).UnixMilli() > autoDel {
 		return errors.New("private_url_expires_cannot_be_later_than_auto_delete_at_starting_from_now")
@@ -127,6 +127,61 @@ func TestHandleUploadFile(test *testing.T) {
 			errType:    utils.ErrorTypeUnsupportedType,
 			statusCode: fiber.StatusUnsupportedMediaType,
 		},
+		{
+			name: "TestOnContentMismatchContentType",
+			file: []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"),
+			headers: map[string]string{
+				store.HeaderFileName:          "image.png",
+				fiber.HeaderContentType:       "image/png",
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeContentMismatch,
+			statusCode: fiber.StatusUnsupportedMediaType,
+		},
+		{
+			name: "TestOnE2eFileNotOctetStream",
+			file: fileByte,
+			headers: map[string]string{
+				store.HeaderFileName:          "secret.txt",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderE2eHeader:         "v1.q1XHz2aFkc0l0bVd",
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeUnsupportedType,
+			statusCode: fiber.StatusUnsupportedMediaType,
+		},
+		{
+			name: "TestOnChecksumMismatch",
+			file: fileByte,
+			headers: map[string]string{
+				store.HeaderFileName:          "checksum.txt",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderChecksumSha256:    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", // sha256 of empty file
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeChecksumMismatch,
+			statusCode: fiber.StatusBadRequest,
+		},
+		{
+			name: "TestOnInvalidChecksum",
+			file: fileByte,
+			headers: map[string]string{
+				store.HeaderFileName:          "checksum.txt",
+				fiber.HeaderContentType:       fiber.MIMETextPlainCharsetUTF8,
+				store.HeaderContentMd5:        "invalid",
+				store.HeaderIsPublic:          "1",
+				store.HeaderAutoDeleteAt:      fmt.Sprintf("%d", time.Now().Add(3*time.Minute).UnixMilli()),
+				store.HeaderPrivateUrlExpires: "10", // 10 seconds
+			},
+			errType:    utils.ErrorTypeInvalidHeaderFile,
+			statusCode: fiber.StatusUnprocessableEntity,
+		},
 		{
 			name: "TestInvalidHeaderFile",
 			file: fileByte,
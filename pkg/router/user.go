@@ -2,11 +2,14 @@ package router
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/afifurrohman-id/tempsy/internal/files/auth"
 	"github.com/afifurrohman-id/tempsy/internal/files/auth/guest"
-	"github.com/afifurrohman-id/tempsy/internal/files/auth/oauth2"
+	"github.com/afifurrohman-id/tempsy/internal/files/auth/identity"
 	"github.com/afifurrohman-id/tempsy/internal/files/models"
 	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
 	"github.com/afifurrohman-id/tempsy/internal/files/utils"
@@ -14,19 +17,18 @@ import (
 	"github.com/gofiber/fiber/v2/log"
 )
 
+var ErrorTooManyConflict = errors.New("too_many_file_with_the_same_name")
+
 func HandleGetGuestToken(ctx *fiber.Ctx) error {
-	trimAuth := strings.TrimPrefix(ctx.Get(fiber.HeaderAuthorization), auth.BearerPrefix)
+	authorization := ctx.Get(fiber.HeaderAuthorization)
 
-	if _, err := guest.ParseToken(trimAuth); err == nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
-			Error: &models.Error{
-				Kind:        utils.ErrorTypeHaveToken,
-				Description: "You already have valid token",
-			},
-		})
+	// guest token is only verified locally, so new guest token is not depend on revocation in storage
+	_, err := guest.ParseToken(strings.TrimPrefix(authorization, auth.BearerPrefix))
+	if err != nil {
+		_, err = identity.Identify(authorization)
 	}
 
-	if _, err := oauth2.GetGoogleAccountInfo(trimAuth); err == nil {
+	if err == nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
 			Error: &models.Error{
 				Kind:        utils.ErrorTypeHaveToken,
@@ -40,32 +42,28 @@ func HandleGetGuestToken(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(&models.GuestToken{
 		AccessToken: token,
-		ExpiresIn:   604800, // 7 days in seconds
+		ExpiresIn:   int(guest.TokenLifetime.Seconds()),
 	})
 }
 
 func HandleGetUserInfo(ctx *fiber.Ctx) error {
-	userinfo := new(models.User)
-
-	token := strings.TrimPrefix(ctx.Get(fiber.HeaderAuthorization), auth.BearerPrefix)
-
-	if claims, err := guest.ParseToken(token); err == nil {
-		userinfo.UserName = claims["jti"].(string)
-	} else {
+	user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+	if errors.Is(err, identity.ErrorUnavailable) {
+		return identityUnavailable(ctx, err)
+	}
+	if err != nil {
 		log.Error(err)
 
-		goUser, err := oauth2.GetGoogleAccountInfo(token)
-		if err != nil {
-			return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
-				Error: &models.Error{
-					Kind:        utils.ErrorTypeInvalidToken,
-					Description: "GuestToken is not valid, Cannot get user info",
-				},
-			})
-		}
-		userinfo.UserName = goUser.UserName
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidToken,
+				Description: "GuestToken is not valid, Cannot get user info",
+			},
+		})
 	}
 
+	userinfo := &models.User{UserName: user.UserName}
+
 	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
 	defer cancel()
 
@@ -76,3 +74,124 @@ func HandleGetUserInfo(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(&userinfo)
 }
+
+// HandleClaimGuest move all files of guest account into Google account after sign in, with the same metadata and versions.
+// Name conflict is resolved by adding number to the file name, and guest token is revoked when all files is transferred
+func HandleClaimGuest(ctx *fiber.Ctx) error {
+	user, err := identity.Identify(ctx.Get(fiber.HeaderAuthorization))
+	if errors.Is(err, identity.ErrorUnavailable) {
+		return identityUnavailable(ctx, err)
+	}
+	if err != nil || user.Guest || user.ApiKey != nil {
+		utils.LogErr(err)
+
+		return ctx.Status(fiber.StatusUnauthorized).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidToken,
+				Description: "Google account token is required to claim guest account",
+			},
+		})
+	}
+
+	guestUser, err := identity.Identify(ctx.Get(auth.HeaderGuestAuthorization))
+	if errors.Is(err, identity.ErrorUnavailable) {
+		return identityUnavailable(ctx, err)
+	}
+	if err != nil || !guestUser.Guest {
+		utils.LogErr(err)
+
+		return ctx.Status(fiber.StatusBadRequest).JSON(&models.ApiError{
+			Error: &models.Error{
+				Kind:        utils.ErrorTypeInvalidToken,
+				Description: "Guest token is not valid, expired or already claimed",
+			},
+		})
+	}
+
+	// many files is moved, it take longer than single file
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.ArchiveTimeout)
+	defer cancel()
+
+	filesData, err := store.ListObjects(storeCtx, guestUser.UserName+"/")
+	utils.Check(err)
+
+	results := make([]*models.FileResult, len(filesData))
+	for i, fileData := range filesData {
+		results[i] = &models.FileResult{Name: fileData.Name[len(guestUser.UserName)+1:]}
+	}
+
+	err = store.Batch(storeCtx, filesData, func(ctx context.Context, i int, fileData *models.DataFile) error {
+		newName, err := claimFile(ctx, fileData.Name, user.UserName, results[i].Name)
+		if err != nil {
+			return err
+		}
+
+		if newName != results[i].Name {
+			results[i].NewName = newName
+		}
+
+		utils.LogErr(store.RevokeGrants(ctx, fileData.Name))
+		return nil
+	})
+	errs := store.BatchErrors(err)
+	if err != nil && errs == nil {
+		log.Panic(err)
+	}
+
+	claimResult := &models.ClaimResult{Results: results}
+	for i, result := range results {
+		if errs == nil || errs[i] == nil {
+			result.Status = models.FileResultTransferred
+			continue
+		}
+
+		log.Error(errs[i])
+
+		result.Status = models.FileResultFailed
+		result.Error = &models.Error{
+			Kind:        "unknown_server_error",
+			Description: "Failed to transfer file, please try again",
+		}
+	}
+
+	// guest token is kept, so failed file can be claimed again
+	if errs == nil {
+		utils.Check(store.RevokeToken(storeCtx, guestUser.UserName, time.Now().Add(guest.TokenLifetime)))
+		claimResult.Revoked = true
+	}
+
+	return ctx.JSON(claimResult)
+}
+
+// identityUnavailable response 503 when token cannot be verified because storage is unavailable, it's not mean the token is invalid
+func identityUnavailable(ctx *fiber.Ctx, err error) error {
+	log.Error(err)
+
+	return ctx.Status(fiber.StatusServiceUnavailable).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeAuthUnavailable,
+			Description: "Cannot verify token at the moment, please try again",
+		},
+	})
+}
+
+// claimFile transfer file to owner with the same name, or with number added to the name when it's already used
+func claimFile(ctx context.Context, filePath, owner, fileName string) (string, error) {
+	for number := 0; number <= store.MaxConflictNumber; number++ {
+		dstName := fileName
+		if number > 0 {
+			dstName = store.ConflictFileName(fileName, number)
+		}
+
+		err := store.TransferObject(ctx, filePath, fmt.Sprintf("%s/%s", owner, dstName))
+		if err == nil {
+			return dstName, nil
+		}
+
+		if !store.IsPreconditionFailed(err) {
+			return "", err
+		}
+	}
+
+	return "", ErrorTooManyConflict
+}
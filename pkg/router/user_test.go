@@ -145,3 +145,49 @@ func TestHandleGetUserInfo(test *testing.T) {
 		assert.Equal(test, utils.ErrorTypeInvalidToken, apiErr.Error.Kind)
 	})
 }
+
+func TestHandleClaimGuest(test *testing.T) {
+	app := fiber.New()
+	app.Post("/guest/claim", HandleClaimGuest)
+
+	token, err := guest.CreateToken(guest.GenerateUsername())
+	require.NoError(test, err)
+
+	tableTests := []struct {
+		name          string
+		authorization string
+	}{
+		{
+			name: "TestOnMissingToken",
+		},
+		{
+			// guest account cannot claim other guest account
+			name:          "TestOnGuestToken",
+			authorization: auth.BearerPrefix + token,
+		},
+	}
+
+	for _, table := range tableTests {
+		test.Run(table.name, func(test *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/guest/claim", nil)
+			req.Header.Set(fiber.HeaderAuthorization, table.authorization)
+			req.Header.Set(auth.HeaderGuestAuthorization, auth.BearerPrefix+token)
+
+			res, err := app.Test(req, 1500*10) // 15 seconds
+			require.NoError(test, err)
+
+			test.Cleanup(func() {
+				utils.LogErr(res.Body.Close())
+			})
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(test, err)
+
+			apiErr := new(models.ApiError)
+			require.NoError(test, json.Unmarshal(body, &apiErr))
+
+			assert.Equal(test, fiber.StatusUnauthorized, res.StatusCode)
+			assert.Equal(test, utils.ErrorTypeInvalidToken, apiErr.Error.Kind)
+		})
+	}
+}
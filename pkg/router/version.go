@@ -0,0 +1,115 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	store "github.com/afifurrohman-id/tempsy/internal/files/storage"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// HandleListVersions list previous content of file from the newest
+func HandleListVersions(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if _, err := store.GetObject(storeCtx, filePath); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileNotFound,
+					Description: fmt.Sprintf("File: %s, Is Not Found", fileName),
+				},
+			})
+		}
+		log.Panic(err)
+	}
+
+	versions, err := store.ListVersions(storeCtx, filePath)
+	utils.Check(err)
+
+	return ctx.JSON(&versions)
+}
+
+// HandleGetVersion download previous content of file
+func HandleGetVersion(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	version, fileByte, err := store.ReadVersion(storeCtx, filePath, ctx.Params("version"))
+	if err != nil {
+		if errors.Is(err, store.ErrorVersionNotFound) {
+			return versionNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	// version is private, must not be stored by shared cache
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	store.SetPublicHeaders(ctx, fileName, version.MimeType)
+	ctx.Set(fiber.HeaderContentLength, fmt.Sprintf("%d", len(fileByte)))
+	store.SetDigestHeaders(ctx, fileByte)
+	if version.E2eHeader != "" {
+		ctx.Set(store.HeaderE2eHeader, version.E2eHeader)
+	}
+
+	return ctx.Send(fileByte)
+}
+
+// HandleRestoreVersion replace content of file with previous content
+func HandleRestoreVersion(ctx *fiber.Ctx) error {
+	var (
+		fileName = store.DecodeFileName(ctx.Params("filename"))
+		filePath = fmt.Sprintf("%s/%s", ctx.Params("username"), fileName)
+	)
+
+	storeCtx, cancel := context.WithTimeout(context.Background(), store.DefaultTimeoutCtx)
+	defer cancel()
+
+	if err := store.RestoreVersion(storeCtx, filePath, ctx.Params("version")); err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+				Error: &models.Error{
+					Kind:        utils.ErrorTypeFileNotFound,
+					Description: fmt.Sprintf("File: %s, Is Not Found", fileName),
+				},
+			})
+		}
+
+		if errors.Is(err, store.ErrorVersionNotFound) {
+			return versionNotFound(ctx, fileName)
+		}
+		log.Panic(err)
+	}
+
+	fileData, err := store.GetObject(storeCtx, filePath)
+	utils.Check(err)
+
+	store.Format(fileData)
+	return ctx.JSON(&fileData)
+}
+
+func versionNotFound(ctx *fiber.Ctx, fileName string) error {
+	return ctx.Status(fiber.StatusNotFound).JSON(&models.ApiError{
+		Error: &models.Error{
+			Kind:        utils.ErrorTypeVersionNotFound,
+			Description: fmt.Sprintf("Version: %s of File: %s, Is Not Found", ctx.Params("version"), fileName),
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package router
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/afifurrohman-id/tempsy/internal/files/models"
+	"github.com/afifurrohman-id/tempsy/internal/files/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetVersion(test *testing.T) {
+	app := fiber.New()
+	app.Get("/api/files/:username/:filename/versions/:version", HandleGetVersion)
+
+	test.Run("TestOnVersionNotFound", func(test *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/api/files/version-test/ok.txt/versions/invalid", nil)
+
+		res, err := app.Test(req, 1500*10) // 15 seconds
+		require.NoError(test, err)
+
+		test.Cleanup(func() {
+			utils.LogErr(res.Body.Close())
+		})
+
+		body, err := io.ReadAll(res.Body)
+		require.NoError(test, err)
+
+		apiRes := new(models.ApiError)
+		require.NoError(test, json.Unmarshal(body, &apiRes))
+
+		assert.Equal(test, fiber.StatusNotFound, res.StatusCode)
+		assert.Equal(test, utils.ErrorTypeVersionNotFound, apiRes.Error.Kind)
+	})
+}